@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"chainhub-api/internal/config"
 	"chainhub-api/internal/db"
 	apihttp "chainhub-api/internal/http"
 	"chainhub-api/internal/http/handlers"
+	"chainhub-api/internal/lockout"
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 func main() {
@@ -24,14 +33,69 @@ func main() {
 	defer dbConn.Close()
 
 	if cfg.RunMigrations {
+		if err := checkDuplicatesBeforeMigrating(dbConn); err != nil {
+			log.Fatalf("migration error: %v", err)
+		}
 		if err := db.RunMigrations(dbConn, cfg.MigrationsPath); err != nil {
 			log.Fatalf("migration error: %v", err)
 		}
 	}
 
-	handler := handlers.New(dbConn, cfg)
+	if err := services.BackfillUsernameSkeletons(dbConn); err != nil {
+		log.Fatalf("username skeleton backfill error: %v", err)
+	}
+
+	keys, err := services.LoadKeySet(cfg.JWTAlgorithm, cfg.JWTSecret, cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
+	if err != nil {
+		log.Fatalf("jwt key error: %v", err)
+	}
+	keys.Issuer = cfg.JWTIssuer
+	keys.Audience = cfg.JWTAudience
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("mailer error: %v", err)
+	}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnRPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.WebAuthnTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.WebAuthnTimeout},
+		},
+	})
+	if err != nil {
+		log.Fatalf("webauthn config error: %v", err)
+	}
+
+	locks, err := lockout.New(cfg, dbConn)
+	if err != nil {
+		log.Fatalf("lockout store error: %v", err)
+	}
+
+	passwords, err := services.NewPasswordPolicy(cfg.PasswordMinScore, cfg.BreachedPasswordsFile)
+	if err != nil {
+		log.Fatalf("password policy error: %v", err)
+	}
+
+	hasher, err := services.NewPasswordHasher(cfg.PasswordHashAlgorithm, cfg.BcryptCost, services.Argon2Params{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	if err != nil {
+		log.Fatalf("password hasher error: %v", err)
+	}
+
+	handler := handlers.New(dbConn, cfg, keys, mail, wa, locks, passwords, hasher)
 	router := apihttp.NewRouter(handler)
 
+	go services.RunAccountDeletion(context.Background(), dbConn, cfg.AccountDeletionInterval)
+
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	log.Printf("listening on %s", addr)
 
@@ -39,3 +103,56 @@ func main() {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+// Migrations that make existing values unique regardless of case.
+const (
+	caseInsensitiveUsernameMigration = 17
+	caseInsensitiveEmailMigration    = 18
+)
+
+// checkDuplicatesBeforeMigrating runs cmd/checkemails' case-only duplicate
+// checks when migration 017 or 018 is still to come, so startup fails with the
+// accounts to fix instead of leaving the schema dirty halfway through the
+// upgrade.
+func checkDuplicatesBeforeMigrating(dbConn *sql.DB) error {
+	version, ok, err := db.MigrationVersion(dbConn)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if version < caseInsensitiveUsernameMigration {
+		names, err := repo.ListUsernames(dbConn)
+		if err != nil {
+			return fmt.Errorf("list usernames: %w", err)
+		}
+		if duplicates := repo.GroupUsernames(names, strings.ToLower); len(duplicates) > 0 {
+			var report strings.Builder
+			for _, group := range duplicates {
+				for _, n := range group {
+					fmt.Fprintf(&report, "\n  user %d\t%s\tcreated %s", n.UserID, n.Username, n.CreatedAt.Format("2006-01-02"))
+				}
+			}
+			return fmt.Errorf("migration 017 needs usernames that are unique regardless of case; rename these accounts first:%s", report.String())
+		}
+	}
+
+	if version < caseInsensitiveEmailMigration {
+		emails, err := repo.ListUserEmails(dbConn)
+		if err != nil {
+			return fmt.Errorf("list emails: %w", err)
+		}
+		if duplicates := repo.GroupUserEmails(emails, strings.ToLower); len(duplicates) > 0 {
+			var report strings.Builder
+			for _, group := range duplicates {
+				for _, e := range group {
+					fmt.Fprintf(&report, "\n  user %d\t%s\tcreated %s", e.UserID, e.Email, e.CreatedAt.Format("2006-01-02"))
+				}
+			}
+			return fmt.Errorf("migration 018 needs emails that are unique regardless of case; merge or rename these accounts first:%s", report.String())
+		}
+	}
+	return nil
+}
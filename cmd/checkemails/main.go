@@ -0,0 +1,83 @@
+// Command checkemails lists accounts whose usernames or emails collide once
+// compared case-insensitively, which keeps migrations 017 and 018 from
+// applying, and accounts whose usernames look alike, which keeps the API from
+// starting. Run it
+// before upgrading and merge or rename the accounts it reports. With
+// EMAIL_CANONICALIZATION enabled it also lists addresses that only collide
+// once canonicalized; those do not block the migration.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"chainhub-api/internal/config"
+	"chainhub-api/internal/db"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	dbConn, err := db.Connect(cfg)
+	if err != nil {
+		log.Fatalf("db error: %v", err)
+	}
+	defer dbConn.Close()
+
+	emails, err := repo.ListUserEmails(dbConn)
+	if err != nil {
+		log.Fatalf("list emails: %v", err)
+	}
+
+	caseDuplicates := repo.GroupUserEmails(emails, strings.ToLower)
+	report("Emails that differ only by case (migration 018 will fail):", caseDuplicates, emailLine)
+
+	if cfg.EmailCanonicalization {
+		canonical := repo.GroupUserEmails(emails, func(email string) string {
+			return services.CanonicalEmail(email, true)
+		})
+		report("Emails that are the same once canonicalized (not blocking):", canonical, emailLine)
+	}
+
+	usernames, err := repo.ListUsernames(dbConn)
+	if err != nil {
+		log.Fatalf("list usernames: %v", err)
+	}
+	caseUsernames := repo.GroupUsernames(usernames, strings.ToLower)
+	report("Usernames that differ only by case (migration 017 will fail):", caseUsernames, usernameLine)
+	lookAlikes := repo.GroupUsernames(usernames, services.UsernameSkeleton)
+	report("Usernames that look alike (the API will not start; rename all but the first):", lookAlikes, usernameLine)
+
+	if len(caseDuplicates) > 0 || len(caseUsernames) > 0 || len(lookAlikes) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("No case-insensitive duplicates or look-alike usernames.")
+}
+
+func emailLine(e repo.UserEmail) string {
+	return fmt.Sprintf("user %d\t%s\tcreated %s", e.UserID, e.Email, e.CreatedAt.Format("2006-01-02"))
+}
+
+func usernameLine(n repo.UserName) string {
+	return fmt.Sprintf("user %d\t%s\tcreated %s", n.UserID, n.Username, n.CreatedAt.Format("2006-01-02"))
+}
+
+func report[T any](title string, duplicates [][]T, line func(T) string) {
+	if len(duplicates) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, group := range duplicates {
+		for _, item := range group {
+			fmt.Println("  " + line(item))
+		}
+		fmt.Println()
+	}
+}
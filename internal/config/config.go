@@ -2,10 +2,23 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// OIDCProvider is an OpenID Connect identity provider users can sign in with.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
 type Config struct {
 	AppEnv    string
 	Port      int
@@ -16,9 +29,74 @@ type Config struct {
 	DBName    string
 	DBSSLMode string
 	JWTSecret string
+	JWTAlgorithm            string
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+	JWTIssuer               string
+	JWTAudience             string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	SessionCacheTTL time.Duration
 	MigrationsPath string
 	RunMigrations  bool
 	FrontendURL    string
+	TrustedProxies []netip.Prefix
+	SIWEDomain     string
+	SIWEChainIDs   []int64
+	SIWENonceTTL   time.Duration
+	Mailer         string
+	MailFrom       string
+	MailOutboxDir  string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUsername   string
+	SMTPPassword   string
+	PasswordResetTTL time.Duration
+	EmailVerificationTTL            time.Duration
+	EmailVerificationResendInterval time.Duration
+	RequireVerifiedEmail            bool
+	EmailCanonicalization           bool
+	TOTPIssuer    string
+	MFAPendingTTL time.Duration
+	MagicLinkTTL            time.Duration
+	MagicLinkResendInterval time.Duration
+	WebAuthnRPID      string
+	WebAuthnRPName    string
+	WebAuthnRPOrigins []string
+	WebAuthnTimeout   time.Duration
+	OIDCProviders   []OIDCProvider
+	OIDCRedirectURL string
+	OIDCStateTTL    time.Duration
+	LockoutStore        string
+	LoginFreeAttempts   int
+	LoginMaxFailures    int
+	LoginIPMaxFailures  int
+	SignupIPMaxAttempts int
+	LoginBackoffBase    time.Duration
+	LoginBackoffMax     time.Duration
+	LockoutDuration     time.Duration
+	LockoutResetAfter   time.Duration
+	UsernameRedirectTTL time.Duration
+	AccountDeletionGraceDays int
+	AccountDeletionInterval  time.Duration
+	ReservedUsernames        []string
+	PasswordMinScore         int
+	BreachedPasswordsFile    string
+	PasswordHashAlgorithm    string
+	BcryptCost               int
+	Argon2Memory             int
+	Argon2Iterations         int
+	Argon2Parallelism        int
+	MaxTreesPerUser          int
+}
+
+// defaultReservedUsernames cover the API's own routes and names that could be
+// mistaken for staff. RESERVED_USERNAMES adds to them.
+var defaultReservedUsernames = []string{
+	"admin", "administrator", "api", "auth", "chainhub", "healthz", "help",
+	"login", "logout", "me", "moderator", "root", "security", "settings",
+	"signup", "staff", "support", "system", "themes", "tree", "trees", "links",
+	"www",
 }
 
 func (c Config) Redacted() Config {
@@ -26,6 +104,12 @@ func (c Config) Redacted() Config {
 	// Add new sensitive fields here to keep logs safe.
 	redacted.DBPassword = obfuscate(redacted.DBPassword)
 	redacted.JWTSecret = obfuscate(redacted.JWTSecret)
+	redacted.SMTPPassword = obfuscate(redacted.SMTPPassword)
+	redacted.OIDCProviders = make([]OIDCProvider, len(c.OIDCProviders))
+	for i, p := range c.OIDCProviders {
+		p.ClientSecret = obfuscate(p.ClientSecret)
+		redacted.OIDCProviders[i] = p
+	}
 	return redacted
 }
 
@@ -50,13 +134,127 @@ func Load() (Config, error) {
 		DBName:    envOrDefault("DB_NAME", "chainhub"),
 		DBSSLMode: dbSSLMode,
 		JWTSecret: envOrDefault("JWT_SECRET", ""),
+		JWTAlgorithm:            envOrDefault("JWT_ALGORITHM", "HS256"),
+		JWTSigningKeyFile:       envOrDefault("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles: stringListOrDefault("JWT_VERIFICATION_KEY_FILES", nil),
+		JWTIssuer:               envOrDefault("JWT_ISSUER", "chainhub"),
+		JWTAudience:             envOrDefault("JWT_AUDIENCE", "chainhub-api"),
+		AccessTokenTTL:  durationOrDefault("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: durationOrDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		SessionCacheTTL: durationOrDefault("SESSION_CACHE_TTL", 30*time.Second),
 		MigrationsPath: envOrDefault("MIGRATIONS_PATH", "file://migrations"),
 		RunMigrations:  boolOrDefault("RUN_MIGRATIONS", true),
 		FrontendURL:    envOrDefault("FRONTEND_URL", "http://localhost:3000"),
+		SIWENonceTTL:   durationOrDefault("SIWE_NONCE_TTL", 10*time.Minute),
+		Mailer:         envOrDefault("MAILER", "outbox"),
+		MailFrom:       envOrDefault("MAIL_FROM", "ChainHub <no-reply@chainhub.local>"),
+		MailOutboxDir:  envOrDefault("MAIL_OUTBOX_DIR", "outbox"),
+		SMTPHost:       envOrDefault("SMTP_HOST", "localhost"),
+		SMTPPort:       intOrDefault("SMTP_PORT", 587),
+		SMTPUsername:   envOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword:   envOrDefault("SMTP_PASSWORD", ""),
+		PasswordResetTTL: durationOrDefault("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:            durationOrDefault("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		EmailVerificationResendInterval: durationOrDefault("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute),
+		RequireVerifiedEmail:            boolOrDefault("REQUIRE_VERIFIED_EMAIL", false),
+		EmailCanonicalization:           boolOrDefault("EMAIL_CANONICALIZATION", false),
+		TOTPIssuer:    envOrDefault("TOTP_ISSUER", "ChainHub"),
+		MFAPendingTTL: durationOrDefault("MFA_PENDING_TTL", 5*time.Minute),
+		MagicLinkTTL:            durationOrDefault("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkResendInterval: durationOrDefault("MAGIC_LINK_RESEND_INTERVAL", time.Minute),
+		WebAuthnRPName:  envOrDefault("WEBAUTHN_RP_NAME", "ChainHub"),
+		WebAuthnTimeout: durationOrDefault("WEBAUTHN_TIMEOUT", 5*time.Minute),
+		OIDCStateTTL:    durationOrDefault("OIDC_STATE_TTL", 10*time.Minute),
+		LockoutStore:        envOrDefault("LOCKOUT_STORE", "postgres"),
+		LoginFreeAttempts:   intOrDefault("LOGIN_FREE_ATTEMPTS", 3),
+		LoginMaxFailures:    intOrDefault("LOGIN_MAX_FAILURES", 10),
+		LoginIPMaxFailures:  intOrDefault("LOGIN_IP_MAX_FAILURES", 100),
+		SignupIPMaxAttempts: intOrDefault("SIGNUP_IP_MAX_ATTEMPTS", 20),
+		LoginBackoffBase:    durationOrDefault("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:     durationOrDefault("LOGIN_BACKOFF_MAX", time.Minute),
+		LockoutDuration:     durationOrDefault("LOCKOUT_DURATION", 15*time.Minute),
+		LockoutResetAfter:   durationOrDefault("LOCKOUT_RESET_AFTER", time.Hour),
+		UsernameRedirectTTL: durationOrDefault("USERNAME_REDIRECT_TTL", 30*24*time.Hour),
+		AccountDeletionGraceDays: intOrDefault("ACCOUNT_DELETION_GRACE_DAYS", 30),
+		AccountDeletionInterval:  durationOrDefault("ACCOUNT_DELETION_INTERVAL", time.Hour),
+		ReservedUsernames:        append(append([]string{}, defaultReservedUsernames...), stringListOrDefault("RESERVED_USERNAMES", nil)...),
+		PasswordMinScore:         intOrDefault("PASSWORD_MIN_SCORE", 3),
+		BreachedPasswordsFile:    envOrDefault("BREACHED_PASSWORDS_FILE", ""),
+		PasswordHashAlgorithm:    envOrDefault("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:               intOrDefault("BCRYPT_COST", 10),
+		Argon2Memory:             intOrDefault("ARGON2_MEMORY_KIB", 19*1024),
+		Argon2Iterations:         intOrDefault("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism:        intOrDefault("ARGON2_PARALLELISM", 1),
+		MaxTreesPerUser:          intOrDefault("MAX_TREES_PER_USER", 20),
+	}
+
+	if cfg.Mailer != "smtp" && cfg.Mailer != "outbox" {
+		return Config{}, fmt.Errorf("MAILER must be smtp or outbox")
+	}
+
+	if cfg.LockoutStore != "postgres" && cfg.LockoutStore != "memory" {
+		return Config{}, fmt.Errorf("LOCKOUT_STORE must be postgres or memory")
+	}
+	if cfg.LoginMaxFailures < 1 || cfg.LoginIPMaxFailures < 1 || cfg.SignupIPMaxAttempts < 1 {
+		return Config{}, fmt.Errorf("LOGIN_MAX_FAILURES, LOGIN_IP_MAX_FAILURES and SIGNUP_IP_MAX_ATTEMPTS must be positive")
+	}
+	if cfg.AccountDeletionGraceDays < 0 || cfg.AccountDeletionInterval <= 0 {
+		return Config{}, fmt.Errorf("ACCOUNT_DELETION_GRACE_DAYS must not be negative and ACCOUNT_DELETION_INTERVAL must be positive")
+	}
+	if cfg.PasswordMinScore < 0 || cfg.PasswordMinScore > 4 {
+		return Config{}, fmt.Errorf("PASSWORD_MIN_SCORE must be between 0 and 4")
+	}
+	if cfg.PasswordHashAlgorithm != "argon2id" && cfg.PasswordHashAlgorithm != "bcrypt" {
+		return Config{}, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt")
+	}
+	if cfg.Argon2Memory < 1 || cfg.Argon2Iterations < 1 || cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > 255 {
+		return Config{}, fmt.Errorf("ARGON2_MEMORY_KIB and ARGON2_ITERATIONS must be positive and ARGON2_PARALLELISM between 1 and 255")
+	}
+	if cfg.MaxTreesPerUser < 1 {
+		return Config{}, fmt.Errorf("MAX_TREES_PER_USER must be positive")
+	}
+
+	cfg.SIWEDomain = envOrDefault("SIWE_DOMAIN", hostOf(cfg.FrontendURL))
+	if cfg.SIWEDomain == "" {
+		return Config{}, fmt.Errorf("SIWE_DOMAIN is required when FRONTEND_URL has no host")
 	}
 
-	if cfg.JWTSecret == "" {
-		return Config{}, fmt.Errorf("JWT_SECRET is required")
+	cfg.WebAuthnRPID = envOrDefault("WEBAUTHN_RP_ID", hostnameOf(cfg.FrontendURL))
+	if cfg.WebAuthnRPID == "" {
+		return Config{}, fmt.Errorf("WEBAUTHN_RP_ID is required when FRONTEND_URL has no host")
+	}
+	cfg.WebAuthnRPOrigins = stringListOrDefault("WEBAUTHN_RP_ORIGINS", []string{strings.TrimRight(cfg.FrontendURL, "/")})
+
+	cfg.OIDCRedirectURL = envOrDefault("OIDC_REDIRECT_URL", strings.TrimRight(cfg.FrontendURL, "/")+"/auth/oidc/callback")
+	providers, err := oidcProviders()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.OIDCProviders = providers
+
+	trustedProxies, err := prefixListOrDefault("TRUSTED_PROXIES", nil)
+	if err != nil {
+		return Config{}, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	chainIDs, err := int64ListOrDefault("SIWE_CHAIN_IDS", []int64{1})
+	if err != nil {
+		return Config{}, fmt.Errorf("SIWE_CHAIN_IDS: %w", err)
+	}
+	cfg.SIWEChainIDs = chainIDs
+
+	switch cfg.JWTAlgorithm {
+	case "HS256":
+		if cfg.JWTSecret == "" {
+			return Config{}, fmt.Errorf("JWT_SECRET is required")
+		}
+	case "ES256", "EdDSA":
+		if cfg.JWTSigningKeyFile == "" {
+			return Config{}, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s", cfg.JWTAlgorithm)
+		}
+	default:
+		return Config{}, fmt.Errorf("JWT_ALGORITHM must be HS256, ES256, or EdDSA")
 	}
 
 	fmt.Printf("Loaded config: %+v\n", cfg.Redacted())
@@ -91,6 +289,116 @@ func boolOrDefault(key string, fallback bool) bool {
 	return fallback
 }
 
+func durationOrDefault(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func stringListOrDefault(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+func int64ListOrDefault(key string, fallback []int64) ([]int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	var list []int64
+	for _, part := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, parsed)
+	}
+	return list, nil
+}
+
+// prefixListOrDefault reads comma-separated CIDR ranges; a bare address is a
+// range of one.
+func prefixListOrDefault(key string, fallback []netip.Prefix) ([]netip.Prefix, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	var list []netip.Prefix
+	for _, part := range stringListOrDefault(key, nil) {
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, prefix.Masked())
+	}
+	return list, nil
+}
+
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// oidcProviders reads the providers named in OIDC_PROVIDERS. Each name NAME is
+// configured through OIDC_NAME_ISSUER, OIDC_NAME_CLIENT_ID,
+// OIDC_NAME_CLIENT_SECRET and optionally OIDC_NAME_SCOPES.
+func oidcProviders() ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	for _, name := range stringListOrDefault("OIDC_PROVIDERS", nil) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := OIDCProvider{
+			Name:         name,
+			Issuer:       envOrDefault(prefix+"ISSUER", ""),
+			ClientID:     envOrDefault(prefix+"CLIENT_ID", ""),
+			ClientSecret: envOrDefault(prefix+"CLIENT_SECRET", ""),
+			Scopes:       stringListOrDefault(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// hostnameOf is hostOf without the port; WebAuthn relying party IDs are bare
+// domains.
+func hostnameOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
 func obfuscate(value string) string {
 	if value == "" {
 		return ""
@@ -56,6 +56,28 @@ func RunMigrations(db *sql.DB, migrationsPath string) error {
 	return nil
 }
 
+// MigrationVersion reports the schema version golang-migrate last recorded,
+// and false if no migration has run yet.
+func MigrationVersion(db *sql.DB) (uint, bool, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version uint
+	err := db.QueryRow(`SELECT version FROM schema_migrations LIMIT 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return version, true, nil
+}
+
 func migrationFilename(migrationsPath string, version uint) string {
 	dir := strings.TrimPrefix(migrationsPath, "file://")
 	if dir == "" {
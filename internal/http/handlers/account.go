@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+)
+
+type updateMeRequest struct {
+	Username        *string `json:"username"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type meResponse struct {
+	userResponse
+	PendingEmail        string     `json:"pending_email,omitempty"`
+	HasPassword         bool       `json:"has_password"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	h.writeMe(w, userID)
+}
+
+// UpdateMe changes the username, email and/or password. Changing the email or
+// password needs the current password when the account has one. A new email
+// only takes effect once verified.
+func (h *Handler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req updateMeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.Username == nil && req.Email == nil && req.Password == nil {
+		writeError(w, http.StatusBadRequest, "username, email or password is required")
+		return
+	}
+
+	var username, usernameSkeleton, email string
+	if req.Username != nil {
+		var err error
+		username, usernameSkeleton, err = h.Usernames.Validate(*req.Username)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Email != nil {
+		email = strings.TrimSpace(*req.Email)
+		if email == "" {
+			writeError(w, http.StatusBadRequest, "email cannot be empty")
+			return
+		}
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	if req.Password != nil && !h.checkNewPassword(w, *req.Password, user.Email.String, user.Username.String, email, username) {
+		return
+	}
+
+	if (req.Email != nil || req.Password != nil) && user.PasswordHash.Valid {
+		if !h.checkCurrentPassword(w, user, req.CurrentPassword) {
+			return
+		}
+	}
+
+	// Every check runs before anything is written, and the writes share one
+	// transaction, so a request that fails changes nothing.
+	var update repo.AccountUpdate
+	if req.Username != nil && username != user.Username.String {
+		update.Username = username
+		update.UsernameSkeleton = usernameSkeleton
+		update.RedirectUntil = time.Now().Add(h.Config.UsernameRedirectTTL)
+	}
+
+	if req.Email != nil && !strings.EqualFold(email, user.Email.String) {
+		taken, err := repo.EmailTaken(h.DB, email, h.canonicalEmail(email), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		if taken {
+			writeError(w, http.StatusConflict, "email already in use")
+			return
+		}
+		update.PendingEmail = email
+	}
+
+	if req.Password != nil {
+		hash, err := h.Hasher.Hash(*req.Password)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to hash password")
+			return
+		}
+		update.PasswordHash = hash
+		// Other devices are signed out; this one stays signed in.
+		update.KeepSessionID, _ = middleware.GetSessionID(r.Context())
+	}
+
+	sessionIDs, err := repo.UpdateAccount(h.DB, userID, update)
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "username already in use")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update account")
+		return
+	}
+	for _, id := range sessionIDs {
+		h.Sessions.MarkRevoked(id)
+	}
+
+	if update.PendingEmail != "" {
+		if _, err := h.startEmailVerification(userID, email); err != nil {
+			log.Printf("account: failed to send verification email to user %d: %v", userID, err)
+		}
+		if user.Email.Valid && user.EmailVerifiedAt.Valid {
+			go h.sendMail(mailer.Message{
+				To:      user.Email.String,
+				Subject: "Your ChainHub email is being changed",
+				Body:    emailChangeNoticeBody(email),
+			})
+		}
+	}
+
+	h.writeMe(w, userID)
+}
+
+// checkCurrentPassword writes an error and reports false unless password is
+// the user's. Wrong guesses count towards the account lockout.
+func (h *Handler) checkCurrentPassword(w http.ResponseWriter, user models.User, password string) bool {
+	if password == "" {
+		writeError(w, http.StatusBadRequest, "current_password is required")
+		return false
+	}
+
+	accountKey := accountThrottleKey(user.ID)
+	wait, err := h.AccountLimiter.Wait(accountKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return false
+	}
+	if checkThrottled(w, wait) {
+		return false
+	}
+
+	if !h.verifyPassword(user, password) {
+		if _, err := h.AccountLimiter.Fail(accountKey); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to record login attempt")
+			return false
+		}
+		writeError(w, http.StatusUnauthorized, "invalid current password")
+		return false
+	}
+	return true
+}
+
+func (h *Handler) writeMe(w http.ResponseWriter, userID int64) {
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	pendingEmail, err := repo.GetPendingEmail(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	deletionAt, err := repo.GetAccountDeletionSchedule(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	resp := meResponse{
+		userResponse: newUserResponse(user),
+		PendingEmail: pendingEmail.String,
+		HasPassword:  user.PasswordHash.Valid,
+	}
+	if deletionAt.Valid {
+		resp.DeletionScheduledAt = &deletionAt.Time
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func emailChangeNoticeBody(newEmail string) string {
+	return fmt.Sprintf(
+		"Someone asked to change the email address of your ChainHub account to %s.\n\n"+
+			"The change takes effect once the new address is verified. "+
+			"If this was not you, change your password and sign out other sessions now.\n",
+		newEmail,
+	)
+}
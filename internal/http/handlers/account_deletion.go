@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/repo"
+)
+
+type deleteMeRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+type accountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// DeleteMe schedules the account for deletion after the grace period and signs
+// it out everywhere. Logging in again before then cancels the deletion.
+func (h *Handler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req deleteMeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	if user.PasswordHash.Valid && !h.checkCurrentPassword(w, user, req.CurrentPassword) {
+		return
+	}
+
+	deleteAt := time.Now().AddDate(0, 0, h.Config.AccountDeletionGraceDays)
+	sessionIDs, err := repo.ScheduleAccountDeletion(h.DB, userID, deleteAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to schedule deletion")
+		return
+	}
+	for _, id := range sessionIDs {
+		h.Sessions.MarkRevoked(id)
+	}
+
+	writeJSON(w, http.StatusAccepted, accountDeletionResponse{DeletionScheduledAt: deleteAt})
+}
+
+// cancelAccountDeletion is called on every successful login.
+func (h *Handler) cancelAccountDeletion(userID int64) {
+	cancelled, err := repo.CancelAccountDeletion(h.DB, userID)
+	if err != nil {
+		log.Printf("account deletion: failed to cancel for user %d: %v", userID, err)
+		return
+	}
+	if cancelled {
+		log.Printf("account deletion: cancelled for user %d by login", userID)
+	}
+}
+
+type exportProfile struct {
+	ID              int64      `json:"id"`
+	Email           string     `json:"email,omitempty"`
+	PendingEmail    string     `json:"pending_email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Username        string     `json:"username,omitempty"`
+	WalletAddress   string     `json:"wallet_address,omitempty"`
+	HasPassword     bool       `json:"has_password"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type exportTree struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Slug      string `json:"slug"`
+	IsDefault bool   `json:"is_default"`
+	treeProfile
+	Theme     ownedThemeResponse `json:"theme"`
+	CreatedAt time.Time          `json:"created_at"`
+	Links     []exportLink       `json:"links"`
+}
+
+type exportLink struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Position  int       `json:"position"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportSession struct {
+	ID         int64      `json:"id"`
+	Device     string     `json:"device"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type accountExport struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Profile    exportProfile      `json:"profile"`
+	Trees      []exportTree       `json:"trees"`
+	Sessions   []exportSession    `json:"sessions"`
+	Identities []identityResponse `json:"identities"`
+	Passkeys   []passkeyResponse  `json:"passkeys"`
+	APITokens  []apiTokenResponse `json:"api_tokens"`
+	// Analytics is always empty for now: ChainHub does not record per-user
+	// analytics yet. The key is here so the archive format stays stable.
+	Analytics []json.RawMessage `json:"analytics"`
+}
+
+// ExportMe returns everything stored about the user, as a ZIP of JSON files
+// by default or as a single JSON document with ?format=json.
+func (h *Handler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	export, err := h.buildAccountExport(userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to export account")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Disposition", `attachment; filename="chainhub-export.json"`)
+		writeJSON(w, http.StatusOK, export)
+		return
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"trees.json", export.Trees},
+		{"sessions.json", export.Sessions},
+		{"identities.json", export.Identities},
+		{"passkeys.json", export.Passkeys},
+		{"api_tokens.json", export.APITokens},
+		{"analytics.json", export.Analytics},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chainhub-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			log.Printf("export: user %d: %v", userID, err)
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			log.Printf("export: user %d: %v", userID, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("export: user %d: %v", userID, err)
+	}
+}
+
+func (h *Handler) buildAccountExport(userID int64) (accountExport, error) {
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+	pendingEmail, err := repo.GetPendingEmail(h.DB, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+
+	export := accountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: exportProfile{
+			ID:            user.ID,
+			Email:         user.Email.String,
+			PendingEmail:  pendingEmail.String,
+			Username:      user.Username.String,
+			WalletAddress: user.WalletAddress.String,
+			HasPassword:   user.PasswordHash.Valid,
+			CreatedAt:     user.CreatedAt,
+		},
+		Trees:      []exportTree{},
+		Sessions:   []exportSession{},
+		Identities: []identityResponse{},
+		Passkeys:   []passkeyResponse{},
+		APITokens:  []apiTokenResponse{},
+		Analytics:  []json.RawMessage{},
+	}
+	if user.EmailVerifiedAt.Valid {
+		export.Profile.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+
+	trees, err := repo.ListTreesByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("trees: %w", err)
+	}
+	links, err := repo.ListAllLinksByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("links: %w", err)
+	}
+	linksByTree := make(map[int64][]exportLink)
+	for _, link := range links {
+		linksByTree[link.TreeID] = append(linksByTree[link.TreeID], exportLink{
+			ID:        link.ID,
+			Title:     link.Title,
+			URL:       link.URL,
+			Position:  link.Position,
+			IsActive:  link.IsActive,
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	for _, tree := range trees {
+		treeLinks := linksByTree[tree.ID]
+		if treeLinks == nil {
+			treeLinks = []exportLink{}
+		}
+		export.Trees = append(export.Trees, exportTree{
+			ID:          tree.ID,
+			Title:       tree.Title,
+			Slug:        tree.Slug,
+			IsDefault:   tree.IsDefault,
+			treeProfile: newTreeProfile(tree),
+			Theme:       newOwnedThemeResponse(tree),
+			CreatedAt:   tree.CreatedAt,
+			Links:       treeLinks,
+		})
+	}
+
+	sessions, err := repo.ListAllSessionsByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("sessions: %w", err)
+	}
+	for _, s := range sessions {
+		es := exportSession{
+			ID:         s.ID,
+			Device:     s.Device,
+			IPAddress:  s.IPAddress,
+			UserAgent:  s.UserAgent,
+			LastSeenAt: s.LastSeenAt,
+			CreatedAt:  s.CreatedAt,
+		}
+		if s.RevokedAt.Valid {
+			es.RevokedAt = &s.RevokedAt.Time
+		}
+		export.Sessions = append(export.Sessions, es)
+	}
+
+	identities, err := repo.ListIdentitiesByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("identities: %w", err)
+	}
+	for _, identity := range identities {
+		export.Identities = append(export.Identities, newIdentityResponse(identity))
+	}
+
+	passkeys, err := repo.ListPasskeysByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("passkeys: %w", err)
+	}
+	for _, passkey := range passkeys {
+		export.Passkeys = append(export.Passkeys, newPasskeyResponse(passkey))
+	}
+
+	tokens, err := repo.ListAPITokensByUser(h.DB, userID)
+	if err != nil {
+		return accountExport{}, fmt.Errorf("api tokens: %w", err)
+	}
+	for _, token := range tokens {
+		export.APITokens = append(export.APITokens, newAPITokenResponse(token))
+	}
+
+	return export, nil
+}
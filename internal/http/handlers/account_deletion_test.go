@@ -0,0 +1,37 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// During the grace period nothing but a fresh login may use the account, so
+// personal access tokens stop working too.
+func TestDeleteMeRevokesAPITokens(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+
+	var pat struct {
+		Token string `json:"token"`
+	}
+	if status := s.doJSON(t, http.MethodPost, "/me/tokens", alice.Token, map[string]interface{}{
+		"name":   "ci",
+		"scopes": []string{"tree:read"},
+	}, &pat); status != http.StatusCreated {
+		t.Fatalf("create token: %d", status)
+	}
+
+	status, raw := s.do(t, http.MethodDelete, "/me", alice.Token, map[string]string{"current_password": testPassword})
+	expectStatus(t, "delete", status, raw, http.StatusAccepted)
+
+	status, raw = s.do(t, http.MethodGet, "/trees", pat.Token, nil)
+	expectStatus(t, "token after scheduling deletion", status, raw, http.StatusUnauthorized)
+
+	var scheduled bool
+	if err := s.DB.QueryRow(`SELECT deletion_scheduled_at IS NOT NULL FROM users WHERE id = $1`, alice.User.ID).Scan(&scheduled); err != nil {
+		t.Fatal(err)
+	}
+	if !scheduled {
+		t.Fatal("deletion was cancelled")
+	}
+}
@@ -0,0 +1,64 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// A PATCH /me that fails on one field must not have applied the others.
+func TestUpdateMeFailureChangesNothing(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	bob := s.signup(t)
+	newName := "renamed" + randomSuffix(t)
+
+	status, raw := s.do(t, http.MethodPatch, "/me", alice.Token, map[string]string{
+		"username":         newName,
+		"email":            bob.User.Email,
+		"current_password": testPassword,
+	})
+	expectStatus(t, "rename with a taken email", status, raw, http.StatusConflict)
+
+	status, raw = s.do(t, http.MethodPatch, "/me", alice.Token, map[string]string{
+		"username":         newName,
+		"password":         "short",
+		"current_password": testPassword,
+	})
+	expectStatus(t, "rename with a weak password", status, raw, http.StatusBadRequest)
+
+	status, raw = s.do(t, http.MethodPatch, "/me", alice.Token, map[string]string{
+		"username":         newName,
+		"password":         "another horse battery staple 43",
+		"current_password": "wrong " + testPassword,
+	})
+	expectStatus(t, "rename with a wrong current password", status, raw, http.StatusUnauthorized)
+
+	var me struct {
+		Username     string `json:"username"`
+		PendingEmail string `json:"pending_email"`
+	}
+	if status := s.doJSON(t, http.MethodGet, "/me", alice.Token, nil, &me); status != http.StatusOK {
+		t.Fatalf("GET /me: %d", status)
+	}
+	if me.Username != alice.User.Username || me.PendingEmail != "" {
+		t.Fatalf("after failed updates: username %q, pending email %q; want %q, none", me.Username, me.PendingEmail, alice.User.Username)
+	}
+
+	var redirects int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM username_redirects WHERE user_id = $1`, alice.User.ID).Scan(&redirects); err != nil {
+		t.Fatal(err)
+	}
+	if redirects != 0 {
+		t.Fatalf("failed updates left %d username redirects", redirects)
+	}
+
+	status, raw = s.do(t, http.MethodPatch, "/me", alice.Token, map[string]string{
+		"username":         newName,
+		"password":         "another horse battery staple 43",
+		"current_password": testPassword,
+	})
+	expectStatus(t, "valid update", status, raw, http.StatusOK)
+	if status := s.doJSON(t, http.MethodGet, "/me", alice.Token, nil, &me); status != http.StatusOK || me.Username != newName {
+		t.Fatalf("after a valid update: %d, username %q, want %q", status, me.Username, newName)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	adminUserListDefaultLimit = 50
+	adminUserListMaxLimit     = 200
+	suspensionReasonMaxLength = 500
+)
+
+type adminUserResponse struct {
+	ID               int64      `json:"id"`
+	Email            string     `json:"email,omitempty"`
+	Username         string     `json:"username,omitempty"`
+	WalletAddress    string     `json:"wallet_address,omitempty"`
+	EmailVerified    bool       `json:"email_verified"`
+	Role             string     `json:"role"`
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type adminUserListResponse struct {
+	Users []adminUserResponse `json:"users"`
+}
+
+type suspendUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+type setUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// AdminListUsers lists accounts, optionally filtered with ?q= (start of email
+// or username), ?role= and ?suspended=true|false, paged with ?limit= and
+// ?offset=.
+func (h *Handler) AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repo.UserFilter{
+		Query: strings.TrimSpace(query.Get("q")),
+		Role:  query.Get("role"),
+		Limit: adminUserListDefaultLimit,
+	}
+
+	if filter.Role != "" && !middleware.IsKnownRole(filter.Role) {
+		writeError(w, http.StatusBadRequest, "unknown role")
+		return
+	}
+	if raw := query.Get("suspended"); raw != "" {
+		suspended, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "suspended must be true or false")
+			return
+		}
+		filter.Suspended = sql.NullBool{Bool: suspended, Valid: true}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > adminUserListMaxLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(adminUserListMaxLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset must not be negative")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	users, err := repo.ListUsers(h.DB, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load users")
+		return
+	}
+
+	respUsers := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		respUsers = append(respUsers, newAdminUserResponse(u))
+	}
+
+	writeJSON(w, http.StatusOK, adminUserListResponse{Users: respUsers})
+}
+
+// AdminSuspendUser blocks an account from logging in, signs it out
+// everywhere, disables its API tokens and takes its tree offline.
+func (h *Handler) AdminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req suspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > suspensionReasonMaxLength {
+		writeError(w, http.StatusBadRequest, "reason must be at most "+strconv.Itoa(suspensionReasonMaxLength)+" characters")
+		return
+	}
+
+	sessionIDs, err := repo.SuspendUser(h.DB, target.ID, sql.NullString{String: reason, Valid: reason != ""})
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to suspend user")
+		return
+	}
+	for _, id := range sessionIDs {
+		h.Sessions.MarkRevoked(id)
+	}
+
+	h.writeAdminUser(w, target.ID)
+}
+
+func (h *Handler) AdminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := repo.UnsuspendUser(h.DB, target.ID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to unsuspend user")
+		return
+	}
+
+	h.writeAdminUser(w, target.ID)
+}
+
+// AdminSetUserRole is for admins only. The user is signed out so their next
+// access token carries the new role.
+func (h *Handler) AdminSetUserRole(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req setUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if !middleware.IsKnownRole(req.Role) {
+		writeError(w, http.StatusBadRequest, "role must be user, moderator or admin")
+		return
+	}
+
+	if req.Role != target.Role {
+		sessionIDs, err := repo.SetUserRole(h.DB, target.ID, req.Role)
+		if err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "user not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to change role")
+			return
+		}
+		for _, id := range sessionIDs {
+			h.Sessions.MarkRevoked(id)
+		}
+	}
+
+	h.writeAdminUser(w, target.ID)
+}
+
+// loadModerationTarget loads the user named by the {id} URL parameter and
+// checks the caller may act on them: never on themselves, and moderators
+// only on plain users.
+func (h *Handler) loadModerationTarget(w http.ResponseWriter, r *http.Request) (repo.AdminUser, bool) {
+	actorID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return repo.AdminUser{}, false
+	}
+
+	targetID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || targetID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return repo.AdminUser{}, false
+	}
+	if targetID == actorID {
+		writeError(w, http.StatusForbidden, "cannot moderate your own account")
+		return repo.AdminUser{}, false
+	}
+
+	target, err := repo.GetAdminUser(h.DB, targetID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return repo.AdminUser{}, false
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return repo.AdminUser{}, false
+	}
+
+	if !middleware.RoleAtLeast(middleware.GetRole(r.Context()), middleware.RoleAdmin) && target.Role != middleware.RoleUser {
+		writeError(w, http.StatusForbidden, "only admins can moderate moderators and admins")
+		return repo.AdminUser{}, false
+	}
+	return target, true
+}
+
+func (h *Handler) writeAdminUser(w http.ResponseWriter, userID int64) {
+	user, err := repo.GetAdminUser(h.DB, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	writeJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+func newAdminUserResponse(u repo.AdminUser) adminUserResponse {
+	resp := adminUserResponse{
+		ID:               u.ID,
+		Email:            u.Email.String,
+		Username:         u.Username.String,
+		WalletAddress:    u.WalletAddress.String,
+		EmailVerified:    u.EmailVerifiedAt.Valid,
+		Role:             u.Role,
+		SuspensionReason: u.SuspensionReason.String,
+		CreatedAt:        u.CreatedAt,
+	}
+	if u.SuspendedAt.Valid {
+		resp.SuspendedAt = &u.SuspendedAt.Time
+	}
+	return resp
+}
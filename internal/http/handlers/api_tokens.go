@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createAPITokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type apiTokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type createAPITokenResponse struct {
+	apiTokenResponse
+	Token string `json:"token"`
+}
+
+type apiTokenListResponse struct {
+	Tokens []apiTokenResponse `json:"tokens"`
+}
+
+func (h *Handler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(req.Scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "name and scopes are required")
+		return
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !middleware.IsKnownScope(scope) {
+			writeError(w, http.StatusBadRequest, "unknown scope: "+scope)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	token, tokenHash, prefix, err := services.GenerateAPIToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+
+	created, err := repo.CreateAPIToken(h.DB, models.APIToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: prefix,
+		TokenHash:   tokenHash,
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+
+	// The token itself is only ever shown in this response.
+	writeJSON(w, http.StatusCreated, createAPITokenResponse{
+		apiTokenResponse: newAPITokenResponse(created),
+		Token:            token,
+	})
+}
+
+func (h *Handler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tokens, err := repo.ListAPITokensByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tokens")
+		return
+	}
+
+	respTokens := make([]apiTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		respTokens = append(respTokens, newAPITokenResponse(token))
+	}
+
+	writeJSON(w, http.StatusOK, apiTokenListResponse{Tokens: respTokens})
+}
+
+func (h *Handler) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || tokenID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid token id")
+		return
+	}
+
+	if err := repo.DeleteAPITokenByIDAndUser(h.DB, tokenID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+func newAPITokenResponse(token models.APIToken) apiTokenResponse {
+	resp := apiTokenResponse{
+		ID:        token.ID,
+		Name:      token.Name,
+		Prefix:    token.TokenPrefix,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if token.ExpiresAt.Valid {
+		resp.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		resp.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return resp
+}
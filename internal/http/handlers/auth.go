@@ -4,13 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
+	"chainhub-api/internal/models"
 	"chainhub-api/internal/repo"
-	"chainhub-api/internal/services"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type signupRequest struct {
@@ -26,6 +25,7 @@ type loginRequest struct {
 
 type authResponse struct {
 	Token string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 	User  userResponse `json:"user"`
 	TreeID int64 `json:"tree_id"`
 }
@@ -34,6 +34,9 @@ type userResponse struct {
 	ID    int64  `json:"id"`
 	Email string `json:"email"`
 	Username string `json:"username"`
+	WalletAddress string `json:"wallet_address,omitempty"`
+	EmailVerified bool `json:"email_verified"`
+	Role string `json:"role"`
 }
 
 func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
@@ -50,18 +53,37 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Password) < 8 {
-		writeError(w, http.StatusBadRequest, "password must be at least 8 characters")
+	username, usernameSkeleton, err := h.Usernames.Validate(username)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !h.checkNewPassword(w, req.Password, email, username) {
 		return
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	ip := clientIP(r)
+	wait, err := h.SignupLimiter.Wait(ip)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check signup attempts")
+		return
+	}
+	if checkThrottled(w, wait) {
+		return
+	}
+	if _, err := h.SignupLimiter.Fail(ip); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record signup attempt")
+		return
+	}
+
+	hash, err := h.Hasher.Hash(req.Password)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to hash password")
 		return
 	}
 
-	user, err := repo.CreateUser(h.DB, email, username, string(hash))
+	user, err := repo.CreateUser(h.DB, email, h.canonicalEmail(email), username, usernameSkeleton, hash)
 	if err != nil {
 		if errors.Is(err, repo.ErrDuplicate) {
 			writeError(w, http.StatusConflict, "email or username already in use")
@@ -71,28 +93,18 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	treeTitle := fmt.Sprintf("%s's Link Tree", username)
-	tree, err := repo.CreateTree(h.DB, user.ID, treeTitle)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create tree")
-		return
+	if _, err := h.startEmailVerification(user.ID, user.Email.String); err != nil {
+		log.Printf("signup: failed to send verification email to user %d: %v", user.ID, err)
 	}
 
-	token, err := services.GenerateJWT(h.Config.JWTSecret, user.ID)
+	treeTitle := fmt.Sprintf("%s's Link Tree", username)
+	tree, err := h.createDefaultTree(user.ID, treeTitle, username)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create token")
+		writeError(w, http.StatusInternalServerError, "failed to create tree")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, authResponse{
-		Token: token,
-		User: userResponse{
-			ID:    user.ID,
-			Email: user.Email.String,
-			Username:  user.Username.String,
-		},
-		TreeID: tree.ID,
-	})
+	h.writeAuthResponse(w, r, http.StatusCreated, user, tree.ID)
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -108,33 +120,101 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := repo.GetUserByEmailOrUsername(h.DB, identifier)
+	ip := clientIP(r)
+	ipWait, err := h.LoginIPLimiter.Wait(ip)
 	if err != nil {
-		if errors.Is(err, repo.ErrNotFound) {
-			writeError(w, http.StatusUnauthorized, "invalid credentials")
-			return
-		}
+		writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if checkThrottled(w, ipWait) {
+		return
+	}
+
+	user, err := repo.GetUserByEmailOrUsername(h.DB, identifier)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
 		writeError(w, http.StatusInternalServerError, "failed to load user")
 		return
 	}
+	found := err == nil
+
+	accountKey := unknownAccountThrottleKey(identifier)
+	if found {
+		accountKey = accountThrottleKey(user.ID)
+	}
+	accountWait, err := h.AccountLimiter.Wait(accountKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if checkThrottled(w, accountWait) {
+		return
+	}
 
-	if !user.PasswordHash.Valid || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(req.Password)) != nil {
-		writeError(w, http.StatusUnauthorized, "invalid credentials")
+	if !found || !h.verifyPassword(user, req.Password) {
+		h.loginFailed(w, ip, accountKey)
 		return
 	}
 
-	token, err := services.GenerateJWT(h.Config.JWTSecret, user.ID)
+	if err := h.AccountLimiter.Reset(accountKey); err != nil {
+		log.Printf("login: failed to reset lockout for user %d: %v", user.ID, err)
+	}
+
+	h.completeLogin(w, r, http.StatusOK, user, 0)
+}
+
+// loginFailed counts a failed attempt against the client IP and the account
+// and rejects the request. The IP count is never reset by a success, so one
+// working account cannot be used to clear it.
+func (h *Handler) loginFailed(w http.ResponseWriter, ip, accountKey string) {
+	ipWait, err := h.LoginIPLimiter.Fail(ip)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record login attempt")
+		return
+	}
+	accountWait, err := h.AccountLimiter.Fail(accountKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to record login attempt")
+		return
+	}
+
+	if wait := max(ipWait, accountWait); wait > 0 {
+		setRetryAfter(w, wait)
+	}
+	writeError(w, http.StatusUnauthorized, "invalid credentials")
+}
+
+// writeAuthResponse opens a session for user and writes the tokens. Every
+// login path ends here, so this is also where suspended accounts are turned
+// away and a pending account deletion is cancelled.
+func (h *Handler) writeAuthResponse(w http.ResponseWriter, r *http.Request, status int, user models.User, treeID int64) {
+	if user.SuspendedAt.Valid {
+		writeError(w, http.StatusForbidden, "account suspended")
+		return
+	}
+
+	h.cancelAccountDeletion(user.ID)
+
+	tokens, err := h.issueTokens(r, user)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to create token")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, authResponse{
-		Token: token,
-		User: userResponse{
-			ID:    user.ID,
-			Email: user.Email.String,
-			Username:  user.Username.String,
-		},
+	writeJSON(w, status, authResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		User:         newUserResponse(user),
+		TreeID:       treeID,
 	})
 }
+
+func newUserResponse(user models.User) userResponse {
+	return userResponse{
+		ID:            user.ID,
+		Email:         user.Email.String,
+		Username:      user.Username.String,
+		WalletAddress: user.WalletAddress.String,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Role:          user.Role,
+	}
+}
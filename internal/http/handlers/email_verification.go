@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	userID, claims, err := services.ParsePurposeToken(h.Keys, token, services.PurposeEmailVerification)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid or expired verification token")
+		return
+	}
+
+	email, _ := claims["email"].(string)
+	if err := repo.MarkEmailVerified(h.DB, userID, email, h.canonicalEmail(email)); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			// The account changed its email after this link was sent.
+			writeError(w, http.StatusBadRequest, "invalid or expired verification token")
+			return
+		}
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "email already in use")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to verify email")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"verified": true})
+}
+
+func (h *Handler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	// A pending email change is what still needs verifying, if there is one.
+	pendingEmail, err := repo.GetPendingEmail(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	email := pendingEmail.String
+	if !pendingEmail.Valid {
+		if !user.Email.Valid {
+			writeError(w, http.StatusBadRequest, "account has no email address")
+			return
+		}
+		if user.EmailVerifiedAt.Valid {
+			writeError(w, http.StatusConflict, "email already verified")
+			return
+		}
+		email = user.Email.String
+	}
+
+	sent, err := h.startEmailVerification(userID, email)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+	if !sent {
+		retryAfter := h.Config.EmailVerificationResendInterval
+		if sentAt, err := repo.GetEmailVerificationSentAt(h.DB, userID); err == nil && sentAt.Valid {
+			retryAfter = time.Until(sentAt.Time.Add(h.Config.EmailVerificationResendInterval))
+		}
+		writeRetryAfter(w, retryAfter, "verification email sent recently, try again later")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]bool{"sent": true})
+}
+
+// RequireVerifiedEmail blocks the wrapped routes for accounts whose email is
+// not verified yet, when Config.RequireVerifiedEmail is on. Wallet-only
+// accounts have no email to verify and are let through.
+func (h *Handler) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.Config.RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, ok := middleware.GetUserID(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		user, err := repo.GetUserByID(h.DB, userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+
+		if user.Email.Valid && !user.EmailVerifiedAt.Valid {
+			writeError(w, http.StatusForbidden, "email verification required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startEmailVerification mails a verification link for email, the user's
+// current or pending address. It reports false without sending if a link went
+// out within the resend interval.
+func (h *Handler) startEmailVerification(userID int64, email string) (bool, error) {
+	notBefore := time.Now().Add(-h.Config.EmailVerificationResendInterval)
+	claimed, err := repo.ClaimEmailVerificationSend(h.DB, userID, notBefore)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	token, err := services.GeneratePurposeToken(
+		h.Keys,
+		services.PurposeEmailVerification,
+		userID,
+		jwt.MapClaims{"email": email},
+		h.Config.EmailVerificationTTL,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	go h.sendMail(mailer.Message{
+		To:      email,
+		Subject: "Verify your ChainHub email",
+		Body:    emailVerificationBody(h.frontendLink("/verify-email", token), h.Config.EmailVerificationTTL),
+	})
+	return true, nil
+}
+
+func emailVerificationBody(link string, ttl time.Duration) string {
+	return fmt.Sprintf(
+		"Welcome to ChainHub!\n\n"+
+			"Confirm your email address with this link. It expires in %s:\n\n%s\n\n"+
+			"If you did not create a ChainHub account, you can ignore this email.\n",
+		ttl,
+		link,
+	)
+}
+
+// canonicalEmail is the form used to keep emails unique, see
+// services.CanonicalEmail.
+func (h *Handler) canonicalEmail(email string) string {
+	return services.CanonicalEmail(email, h.Config.EmailCanonicalization)
+}
@@ -4,16 +4,76 @@ import (
 	"database/sql"
 
 	"chainhub-api/internal/config"
+	"chainhub-api/internal/lockout"
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/services"
+
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 type Handler struct {
-	DB     *sql.DB
-	Config config.Config
+	DB        *sql.DB
+	Config    config.Config
+	Sessions  *services.SessionCache
+	Keys      *services.KeySet
+	Mailer    mailer.Mailer
+	WebAuthn  *webauthn.WebAuthn
+	OIDC      map[string]*services.OIDCClient
+	APITokens *services.APITokenAuthenticator
+
+	// Brute-force throttles. LoginIPLimiter and SignupLimiter are keyed by
+	// client IP, AccountLimiter by accountThrottleKey.
+	LoginIPLimiter *lockout.Limiter
+	AccountLimiter *lockout.Limiter
+	SignupLimiter  *lockout.Limiter
+
+	Usernames *services.UsernamePolicy
+	Passwords *services.PasswordPolicy
+	Hasher    *services.PasswordHasher
 }
 
-func New(db *sql.DB, cfg config.Config) *Handler {
+func New(db *sql.DB, cfg config.Config, keys *services.KeySet, mail mailer.Mailer, wa *webauthn.WebAuthn, locks lockout.Store, passwords *services.PasswordPolicy, hasher *services.PasswordHasher) *Handler {
+	oidcClients := make(map[string]*services.OIDCClient, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		oidcClients[p.Name] = services.NewOIDCClient(p.Name, p.Issuer, p.ClientID, p.ClientSecret, cfg.OIDCRedirectURL, p.Scopes)
+	}
+
 	return &Handler{
-		DB:     db,
-		Config: cfg,
+		DB:        db,
+		Config:    cfg,
+		Keys:      keys,
+		Mailer:    mail,
+		WebAuthn:  wa,
+		OIDC:      oidcClients,
+		APITokens: services.NewAPITokenAuthenticator(db),
+		Usernames: services.NewUsernamePolicy(cfg.ReservedUsernames),
+		Passwords: passwords,
+		Hasher:    hasher,
+		LoginIPLimiter: lockout.NewLimiter(locks, "login-ip", lockout.Policy{
+			FreeAttempts:    cfg.LoginIPMaxFailures / 2,
+			BackoffBase:     cfg.LoginBackoffBase,
+			BackoffMax:      cfg.LoginBackoffMax,
+			MaxFailures:     cfg.LoginIPMaxFailures,
+			LockoutDuration: cfg.LockoutDuration,
+			ResetAfter:      cfg.LockoutResetAfter,
+		}),
+		AccountLimiter: lockout.NewLimiter(locks, "account", lockout.Policy{
+			FreeAttempts:    cfg.LoginFreeAttempts,
+			BackoffBase:     cfg.LoginBackoffBase,
+			BackoffMax:      cfg.LoginBackoffMax,
+			MaxFailures:     cfg.LoginMaxFailures,
+			LockoutDuration: cfg.LockoutDuration,
+			ResetAfter:      cfg.LockoutResetAfter,
+		}),
+		// Every signup counts, not only failed ones.
+		SignupLimiter: lockout.NewLimiter(locks, "signup-ip", lockout.Policy{
+			FreeAttempts:    cfg.SignupIPMaxAttempts / 2,
+			BackoffBase:     cfg.LoginBackoffBase,
+			BackoffMax:      cfg.LoginBackoffMax,
+			MaxFailures:     cfg.SignupIPMaxAttempts,
+			LockoutDuration: cfg.LockoutDuration,
+			ResetAfter:      cfg.LockoutResetAfter,
+		}),
+		Sessions: services.NewSessionCache(db, cfg.SessionCacheTTL),
 	}
 }
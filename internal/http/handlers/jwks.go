@@ -0,0 +1,9 @@
+package handlers
+
+import "net/http"
+
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Verifiers should refetch often enough to pick up rotated keys.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, h.Keys.JWKS())
+}
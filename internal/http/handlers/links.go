@@ -8,12 +8,14 @@ import (
 	"strings"
 
 	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
 	"chainhub-api/internal/repo"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type createLinkRequest struct {
+	TreeID   *int64 `json:"tree_id"`
 	Title    string `json:"title"`
 	URL      string `json:"url"`
 	Position int    `json:"position"`
@@ -56,7 +58,23 @@ func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
 		isActive = *req.IsActive
 	}
 
-	link, err := repo.CreateLinkByUser(h.DB, userID, title, url, req.Position, isActive)
+	var treeID int64
+	if req.TreeID != nil {
+		treeID = *req.TreeID
+	} else {
+		tree, err := repo.GetDefaultTree(h.DB, userID)
+		if err != nil {
+			if errors.Is(err, repo.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "tree not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to create link")
+			return
+		}
+		treeID = tree.ID
+	}
+
+	link, err := repo.CreateLinkByUser(h.DB, userID, treeID, title, url, req.Position, isActive)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "tree not found")
@@ -65,13 +83,9 @@ func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to create link")
 		return
 	}
+	h.autoPublishTree(link.TreeID, userID)
 
-	writeJSON(w, http.StatusCreated, linkResponse{
-		ID:       link.ID,
-		Title:    link.Title,
-		URL:      link.URL,
-		Position: link.Position,
-	})
+	writeJSON(w, http.StatusCreated, newLinkResponse(link))
 }
 
 func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
@@ -81,13 +95,18 @@ func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := strings.TrimSpace(r.URL.Query().Get("username"))
-	if username == "" {
-		writeError(w, http.StatusBadRequest, "username is required")
-		return
+	var tree models.Tree
+	var err error
+	if raw := r.URL.Query().Get("tree_id"); raw != "" {
+		treeID, parseErr := strconv.ParseInt(raw, 10, 64)
+		if parseErr != nil || treeID <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid tree id")
+			return
+		}
+		tree, err = repo.GetTreeByIDAndUser(h.DB, treeID, userID)
+	} else {
+		tree, err = repo.GetDefaultTree(h.DB, userID)
 	}
-
-	links, err := repo.ListLinksByUsernameAndUser(h.DB, username, userID)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "tree not found")
@@ -97,14 +116,15 @@ func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	links, err := repo.ListLinksByTreeIDAndUser(h.DB, tree.ID, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load links")
+		return
+	}
+
 	respLinks := make([]linkResponse, 0, len(links))
 	for _, link := range links {
-		respLinks = append(respLinks, linkResponse{
-			ID:       link.ID,
-			Title:    link.Title,
-			URL:      link.URL,
-			Position: link.Position,
-		})
+		respLinks = append(respLinks, newLinkResponse(link))
 	}
 
 	writeJSON(w, http.StatusOK, linkListResponse{Links: respLinks})
@@ -145,13 +165,9 @@ func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to update link")
 		return
 	}
+	h.autoPublishTree(link.TreeID, userID)
 
-	writeJSON(w, http.StatusOK, linkResponse{
-		ID:       link.ID,
-		Title:    link.Title,
-		URL:      link.URL,
-		Position: link.Position,
-	})
+	writeJSON(w, http.StatusOK, newLinkResponse(link))
 }
 
 func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
@@ -167,7 +183,7 @@ func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = repo.DeleteLinkByIDAndUser(h.DB, linkID, userID)
+	treeID, err := repo.DeleteLinkByIDAndUser(h.DB, linkID, userID)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, http.StatusNotFound, "link not found")
@@ -176,6 +192,7 @@ func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to delete link")
 		return
 	}
+	h.autoPublishTree(treeID, userID)
 
 	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
 }
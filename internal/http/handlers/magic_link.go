@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type magicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+const magicLinkMessage = "if a verified account exists for that email, a login link has been sent"
+
+// RequestMagicLink emails a single-use login link. Links only go to verified
+// addresses, so an unverified account cannot be entered by whoever controls
+// the inbox it was registered with.
+func (h *Handler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req magicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	// As with password resets, the response does not reveal whether the
+	// account exists.
+	user, err := repo.GetUserByEmail(h.DB, email)
+	if err == nil && user.EmailVerifiedAt.Valid {
+		if wait, err := h.AccountLimiter.Wait(accountThrottleKey(user.ID)); err != nil || wait > 0 {
+			// A locked account gets no link; see MagicLinkCallback.
+			if err != nil {
+				log.Printf("magic link: failed to check lockout for user %d: %v", user.ID, err)
+			}
+		} else if err := h.sendMagicLink(user.ID, user.Email.String); err != nil {
+			log.Printf("magic link: failed to create link for user %d: %v", user.ID, err)
+		}
+	} else if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		log.Printf("magic link: failed to load user: %v", err)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": magicLinkMessage})
+}
+
+func (h *Handler) MagicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	userID, claims, err := services.ParsePurposeToken(h.Keys, token, services.PurposeMagicLink)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid or expired login link")
+		return
+	}
+
+	// A link does not get around a lockout from failed password attempts.
+	wait, err := h.AccountLimiter.Wait(accountThrottleKey(userID))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if checkThrottled(w, wait) {
+		return
+	}
+
+	jti, _ := claims["jti"].(string)
+	email, _ := claims["email"].(string)
+	if err := repo.ConsumeMagicLink(h.DB, userID, jti, email); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusUnauthorized, "invalid or expired login link")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to redeem login link")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	// The link stands in for the password only; TOTP still applies.
+	h.completeLogin(w, r, http.StatusOK, user, 0)
+}
+
+// sendMagicLink mails a login link unless one went out within the resend
+// interval.
+func (h *Handler) sendMagicLink(userID int64, email string) error {
+	jti, err := services.GenerateNonce()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	created, err := repo.CreateMagicLink(h.DB, userID, jti, now.Add(h.Config.MagicLinkTTL), now.Add(-h.Config.MagicLinkResendInterval))
+	if err != nil || !created {
+		return err
+	}
+
+	token, err := services.GeneratePurposeToken(
+		h.Keys,
+		services.PurposeMagicLink,
+		userID,
+		jwt.MapClaims{"jti": jti, "email": email},
+		h.Config.MagicLinkTTL,
+	)
+	if err != nil {
+		return err
+	}
+
+	go h.sendMail(mailer.Message{
+		To:      email,
+		Subject: "Your ChainHub login link",
+		Body:    magicLinkBody(h.frontendLink("/magic-link", token), h.Config.MagicLinkTTL),
+	})
+	return nil
+}
+
+func magicLinkBody(link string, ttl time.Duration) string {
+	return fmt.Sprintf(
+		"Use this link to log in to ChainHub. It expires in %s and can only be used once:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+		ttl,
+		link,
+	)
+}
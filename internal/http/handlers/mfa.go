@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+const recoveryCodeCount = 10
+
+type totpEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type totpConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// totpDisableRequest re-authenticates with the password, or, for accounts
+// without one, with a current code or a recovery code.
+type totpDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaPendingResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// completeLogin finishes a first-factor login. Accounts with TOTP enabled get
+// a short-lived mfa_pending token to exchange at /auth/mfa/verify instead of
+// a session. Suspended accounts are turned away first, so the second factor
+// cannot be probed or recovery codes spent on an account that cannot sign in.
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, status int, user models.User, treeID int64) {
+	if user.SuspendedAt.Valid {
+		writeError(w, http.StatusForbidden, "account suspended")
+		return
+	}
+
+	enabled, err := repo.IsTOTPEnabled(h.DB, user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	if enabled {
+		token, err := services.GeneratePurposeToken(h.Keys, services.PurposeMFAPending, user.ID, nil, h.Config.MFAPendingTTL)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to create token")
+			return
+		}
+		writeJSON(w, http.StatusOK, mfaPendingResponse{MFARequired: true, MFAToken: token})
+		return
+	}
+
+	h.writeAuthResponse(w, r, status, user, treeID)
+}
+
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		writeError(w, http.StatusBadRequest, "mfa_token and code or recovery_code are required")
+		return
+	}
+
+	userID, _, err := services.ParsePurposeToken(h.Keys, req.MFAToken, services.PurposeMFAPending)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid or expired mfa token")
+		return
+	}
+
+	accountKey := accountThrottleKey(userID)
+	wait, err := h.AccountLimiter.Wait(accountKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+		return
+	}
+	if checkThrottled(w, wait) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to verify code")
+		return
+	}
+	if !ok {
+		// Wrong codes count towards the same lockout as wrong passwords.
+		if _, err := h.AccountLimiter.Fail(accountKey); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to record login attempt")
+			return
+		}
+		writeError(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	if err := h.AccountLimiter.Reset(accountKey); err != nil {
+		log.Printf("mfa: failed to reset lockout for user %d: %v", userID, err)
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusOK, user, 0)
+}
+
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create secret")
+		return
+	}
+
+	if err := repo.CreatePendingTOTP(h.DB, userID, secret); err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to store secret")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, totpEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: services.TOTPURI(h.Config.TOTPIssuer, accountLabel(user), secret),
+	})
+}
+
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req totpConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	cred, err := repo.GetTOTPCredential(h.DB, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "no pending two-factor enrollment")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load secret")
+		return
+	}
+	if cred.ConfirmedAt.Valid {
+		writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	step, ok := services.ValidateTOTP(cred.Secret, req.Code, time.Now())
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	codes, err := services.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create recovery codes")
+		return
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, services.HashToken(code))
+	}
+
+	if err := repo.ConfirmTOTP(h.DB, userID, step, hashes); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusConflict, "two-factor authentication is already enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to enable two-factor authentication")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req totpDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	if user.PasswordHash.Valid {
+		if req.Password == "" {
+			writeError(w, http.StatusBadRequest, "password is required")
+			return
+		}
+		if !h.verifyPassword(user, req.Password) {
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+	} else {
+		if req.Code == "" && req.RecoveryCode == "" {
+			writeError(w, http.StatusBadRequest, "code or recovery_code is required")
+			return
+		}
+		// Codes are short enough to guess, so they share the login lockout.
+		accountKey := accountThrottleKey(userID)
+		wait, err := h.AccountLimiter.Wait(accountKey)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check login attempts")
+			return
+		}
+		if checkThrottled(w, wait) {
+			return
+		}
+		ok, err := h.checkSecondFactor(userID, req.Code, req.RecoveryCode)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to verify code")
+			return
+		}
+		if !ok {
+			if _, err := h.AccountLimiter.Fail(accountKey); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to record login attempt")
+				return
+			}
+			writeError(w, http.StatusUnauthorized, "invalid code")
+			return
+		}
+		if err := h.AccountLimiter.Reset(accountKey); err != nil {
+			log.Printf("mfa: failed to reset lockout for user %d: %v", userID, err)
+		}
+	}
+
+	if err := repo.DeleteTOTP(h.DB, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to disable two-factor authentication")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery code.
+func (h *Handler) checkSecondFactor(userID int64, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		hash := services.HashToken(services.NormalizeRecoveryCode(recoveryCode))
+		return repo.UseRecoveryCode(h.DB, userID, hash)
+	}
+
+	cred, err := repo.GetTOTPCredential(h.DB, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !cred.ConfirmedAt.Valid {
+		return false, nil
+	}
+
+	step, ok := services.ValidateTOTP(cred.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return repo.UseTOTPStep(h.DB, userID, step)
+}
+
+// accountLabel names the account in authenticator apps.
+func accountLabel(user models.User) string {
+	switch {
+	case user.Email.Valid:
+		return user.Email.String
+	case user.Username.Valid:
+		return user.Username.String
+	default:
+		return strings.ToLower(user.WalletAddress.String)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"chainhub-api/internal/testutil"
+)
+
+// enableTOTP turns on two-factor authentication for the account and returns
+// its recovery codes.
+func enableTOTP(t *testing.T, s *testServer, account testAccount) []string {
+	t.Helper()
+
+	var enroll struct {
+		Secret string `json:"secret"`
+	}
+	if status := s.doJSON(t, http.MethodPost, "/me/mfa/totp", account.Token, nil, &enroll); status != http.StatusCreated {
+		t.Fatalf("enroll: %d", status)
+	}
+	code, err := testutil.TOTPCode(enroll.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if status := s.doJSON(t, http.MethodPost, "/me/mfa/totp/confirm", account.Token, map[string]string{"code": code}, &confirm); status != http.StatusOK {
+		t.Fatalf("confirm: %d", status)
+	}
+	return confirm.RecoveryCodes
+}
+
+func TestDisableTOTPWithPassword(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	recoveryCodes := enableTOTP(t, s, alice)
+
+	// An account with a password cannot skip it with a code.
+	status, raw := s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"recovery_code": recoveryCodes[0]})
+	expectStatus(t, "recovery code instead of password", status, raw, http.StatusBadRequest)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"password": "wrong " + testPassword})
+	expectStatus(t, "wrong password", status, raw, http.StatusUnauthorized)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"password": testPassword})
+	expectStatus(t, "password", status, raw, http.StatusOK)
+}
+
+func TestDisableTOTPWithoutPassword(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	recoveryCodes := enableTOTP(t, s, alice)
+	if _, err := s.DB.Exec(`UPDATE users SET password_hash = NULL WHERE id = $1`, alice.User.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	status, raw := s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{})
+	expectStatus(t, "no code", status, raw, http.StatusBadRequest)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"code": "000000x"})
+	expectStatus(t, "wrong code", status, raw, http.StatusUnauthorized)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"recovery_code": "not-a-code"})
+	expectStatus(t, "wrong recovery code", status, raw, http.StatusUnauthorized)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/mfa/totp", alice.Token, map[string]string{"recovery_code": recoveryCodes[0]})
+	expectStatus(t, "recovery code", status, raw, http.StatusOK)
+
+	var enabled bool
+	if err := s.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM totp_credentials WHERE user_id = $1)`, alice.User.ID).Scan(&enabled); err != nil {
+		t.Fatal(err)
+	}
+	if enabled {
+		t.Fatal("two-factor authentication still enabled")
+	}
+}
+
+// A suspended account gets no mfa_token to try codes against.
+func TestLoginSuspendedAccountWithTOTP(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	enableTOTP(t, s, alice)
+	if _, err := s.DB.Exec(`UPDATE users SET suspended_at = NOW() WHERE id = $1`, alice.User.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	status, raw := s.do(t, http.MethodPost, "/login", "", map[string]string{
+		"email":    alice.User.Email,
+		"password": testPassword,
+	})
+	expectStatus(t, "login", status, raw, http.StatusForbidden)
+	if strings.Contains(string(raw), "mfa_token") {
+		t.Fatalf("suspended login returned an mfa token: %s", raw)
+	}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type oidcStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type oidcCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+type identityResponse struct {
+	ID          int64      `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+type identityListResponse struct {
+	Identities []identityResponse `json:"identities"`
+}
+
+func (h *Handler) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.OIDC))
+	for name := range h.OIDC {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeJSON(w, http.StatusOK, map[string][]string{"providers": names})
+}
+
+func (h *Handler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	h.startOIDC(w, r, sql.NullInt64{})
+}
+
+// StartOIDCLink begins linking another provider account to the signed-in user.
+// The callback is the same as for login.
+func (h *Handler) StartOIDCLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	h.startOIDC(w, r, sql.NullInt64{Int64: userID, Valid: true})
+}
+
+func (h *Handler) startOIDC(w http.ResponseWriter, r *http.Request, userID sql.NullInt64) {
+	client, ok := h.OIDC[chi.URLParam(r, "provider")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown provider")
+		return
+	}
+
+	state, err := services.GenerateNonce()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create state")
+		return
+	}
+	nonce, err := services.GenerateNonce()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create nonce")
+		return
+	}
+	verifier := services.GeneratePKCEVerifier()
+
+	authURL, err := client.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		writeError(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	err = repo.CreateOIDCState(h.DB, models.OIDCState{
+		State:        state,
+		Provider:     client.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(h.Config.OIDCStateTTL),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store state")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oidcStartResponse{AuthorizationURL: authURL, State: state})
+}
+
+// OIDCCallback finishes a login or link started by StartOIDCLogin or
+// StartOIDCLink. A provider account is only attached to an existing ChainHub
+// account by email when both sides have verified that email.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	var req oidcCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.State == "" || req.Code == "" {
+		writeError(w, http.StatusBadRequest, "state and code are required")
+		return
+	}
+
+	state, err := repo.ConsumeOIDCState(h.DB, req.State)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid or expired state")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load state")
+		return
+	}
+
+	client, ok := h.OIDC[state.Provider]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown provider")
+		return
+	}
+
+	identity, err := client.Exchange(r.Context(), req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidIDToken) {
+			writeError(w, http.StatusUnauthorized, "invalid id token")
+			return
+		}
+		log.Printf("oidc: %v", err)
+		writeError(w, http.StatusBadGateway, "failed to complete provider login")
+		return
+	}
+
+	verifiedEmail := sql.NullString{String: identity.Email, Valid: identity.EmailVerified}
+
+	if state.UserID.Valid {
+		linked, err := repo.CreateIdentity(h.DB, state.UserID.Int64, client.Name, identity.Subject, verifiedEmail)
+		if err != nil {
+			if errors.Is(err, repo.ErrDuplicate) {
+				writeError(w, http.StatusConflict, "this provider account is already linked")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to link identity")
+			return
+		}
+		writeJSON(w, http.StatusCreated, newIdentityResponse(linked))
+		return
+	}
+
+	existing, err := repo.GetIdentity(h.DB, client.Name, identity.Subject)
+	if err == nil {
+		user, err := repo.GetUserByID(h.DB, existing.UserID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		h.completeLogin(w, r, http.StatusOK, user, 0)
+		return
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "failed to load identity")
+		return
+	}
+
+	if verifiedEmail.Valid {
+		user, err := repo.GetUserByEmail(h.DB, verifiedEmail.String)
+		if err == nil {
+			if !user.EmailVerifiedAt.Valid {
+				writeError(w, http.StatusConflict, "an account with this email exists; log in and link this provider from your account")
+				return
+			}
+			if _, err := repo.CreateIdentity(h.DB, user.ID, client.Name, identity.Subject, verifiedEmail); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to link identity")
+				return
+			}
+			h.completeLogin(w, r, http.StatusOK, user, 0)
+			return
+		}
+		if !errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+	}
+
+	var emailCanonical sql.NullString
+	if verifiedEmail.Valid {
+		emailCanonical = sql.NullString{String: h.canonicalEmail(verifiedEmail.String), Valid: true}
+	}
+	user, err := repo.CreateUserWithIdentity(h.DB, client.Name, identity.Subject, verifiedEmail, emailCanonical)
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "account already exists, try again")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to create user")
+		return
+	}
+
+	treeTitle := "My Link Tree"
+	if name := strings.TrimSpace(identity.Name); name != "" {
+		treeTitle = fmt.Sprintf("%s's Link Tree", name)
+	}
+	tree, err := h.createDefaultTree(user.ID, treeTitle, identity.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create tree")
+		return
+	}
+
+	h.completeLogin(w, r, http.StatusCreated, user, tree.ID)
+}
+
+func (h *Handler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	identities, err := repo.ListIdentitiesByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load identities")
+		return
+	}
+
+	respIdentities := make([]identityResponse, 0, len(identities))
+	for _, identity := range identities {
+		respIdentities = append(respIdentities, newIdentityResponse(identity))
+	}
+
+	writeJSON(w, http.StatusOK, identityListResponse{Identities: respIdentities})
+}
+
+func (h *Handler) DeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	identityID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || identityID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid identity id")
+		return
+	}
+
+	identities, err := repo.ListIdentitiesByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load identities")
+		return
+	}
+	found := false
+	for _, identity := range identities {
+		if identity.ID == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "identity not found")
+		return
+	}
+
+	if err := repo.DeleteIdentityByIDAndUser(h.DB, identityID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusConflict, "cannot remove the only login method")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete identity")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+func newIdentityResponse(identity models.Identity) identityResponse {
+	resp := identityResponse{
+		ID:        identity.ID,
+		Provider:  identity.Provider,
+		Email:     identity.Email.String,
+		CreatedAt: identity.CreatedAt,
+	}
+	if identity.LastLoginAt.Valid {
+		resp.LastLoginAt = &identity.LastLoginAt.Time
+	}
+	return resp
+}
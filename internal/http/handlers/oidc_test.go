@@ -0,0 +1,239 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"chainhub-api/internal/config"
+	"chainhub-api/internal/testutil"
+)
+
+const testOIDCProviderName = "fake"
+
+func newOIDCTestServer(t *testing.T) (*testServer, *testutil.OIDCProvider) {
+	t.Helper()
+
+	provider, err := testutil.NewOIDCProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.OIDCProviders = []config.OIDCProvider{{
+			Name:         testOIDCProviderName,
+			Issuer:       provider.Issuer(),
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			Scopes:       []string{"openid", "email", "profile"},
+		}}
+	})
+	return s, provider
+}
+
+// startOIDC begins a login (or, with token, a link) and has the provider
+// approve it as user. It returns the state and code for the callback.
+func startOIDC(t *testing.T, s *testServer, provider *testutil.OIDCProvider, token string, user testutil.OIDCUser) (string, string) {
+	t.Helper()
+
+	path := "/auth/oidc/" + testOIDCProviderName + "/start"
+	if token != "" {
+		path = "/me/identities/" + testOIDCProviderName
+	}
+	var start struct {
+		AuthorizationURL string `json:"authorization_url"`
+		State            string `json:"state"`
+	}
+	status, raw := s.do(t, http.MethodPost, path, token, nil)
+	expectStatus(t, "start", status, raw, http.StatusOK)
+	if err := json.Unmarshal(raw, &start); err != nil {
+		t.Fatal(err)
+	}
+
+	code, state, err := provider.Authorize(start.AuthorizationURL, user)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if state != start.State {
+		t.Fatalf("provider returned state %q, want %q", state, start.State)
+	}
+	return state, code
+}
+
+func oidcCallback(t *testing.T, s *testServer, state, code string) (int, []byte) {
+	t.Helper()
+
+	return s.do(t, http.MethodPost, "/auth/oidc/callback", "", map[string]string{"state": state, "code": code})
+}
+
+func countIdentities(t *testing.T, s *testServer, userID int64) int {
+	t.Helper()
+
+	var n int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM identities WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOIDCLoginCreatesThenFindsAccount(t *testing.T) {
+	s, provider := newOIDCTestServer(t)
+	user := testutil.OIDCUser{Subject: "sub-" + randomSuffix(t), Name: "Carol"}
+
+	state, code := startOIDC(t, s, provider, "", user)
+	status, raw := oidcCallback(t, s, state, code)
+	expectStatus(t, "first login", status, raw, http.StatusCreated)
+	var first testAccount
+	if err := json.Unmarshal(raw, &first); err != nil {
+		t.Fatal(err)
+	}
+
+	state, code = startOIDC(t, s, provider, "", user)
+	status, raw = oidcCallback(t, s, state, code)
+	expectStatus(t, "second login", status, raw, http.StatusOK)
+	var second testAccount
+	if err := json.Unmarshal(raw, &second); err != nil {
+		t.Fatal(err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Fatalf("second login reached user %d, want %d", second.User.ID, first.User.ID)
+	}
+}
+
+func TestOIDCCallbackStateMismatch(t *testing.T) {
+	s, provider := newOIDCTestServer(t)
+	state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{Subject: "sub-" + randomSuffix(t)})
+
+	status, raw := oidcCallback(t, s, "not-"+state, code)
+	expectStatus(t, "unknown state", status, raw, http.StatusBadRequest)
+
+	status, raw = oidcCallback(t, s, state, code)
+	expectStatus(t, "matching state", status, raw, http.StatusCreated)
+
+	// A state is good for one callback only.
+	status, raw = oidcCallback(t, s, state, code)
+	expectStatus(t, "reused state", status, raw, http.StatusBadRequest)
+}
+
+func TestOIDCCallbackNonceMismatch(t *testing.T) {
+	s, provider := newOIDCTestServer(t)
+	state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{
+		Subject: "sub-" + randomSuffix(t),
+		Nonce:   "nonce-from-another-login",
+	})
+
+	status, raw := oidcCallback(t, s, state, code)
+	expectStatus(t, "callback", status, raw, http.StatusUnauthorized)
+}
+
+// The code verifier never leaves the server; if the one stored with the state
+// does not match the challenge sent to the provider, the code is worthless.
+func TestOIDCCallbackPKCEVerifier(t *testing.T) {
+	s, provider := newOIDCTestServer(t)
+	state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{Subject: "sub-" + randomSuffix(t)})
+
+	if _, err := s.DB.Exec(
+		`UPDATE oidc_states SET code_verifier = $1 WHERE state = $2`,
+		"a-different-verifier-a-different-verifier-a-different", state,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	status, raw := oidcCallback(t, s, state, code)
+	expectStatus(t, "callback", status, raw, http.StatusBadGateway)
+}
+
+func TestOIDCLinksExistingAccountOnlyWithVerifiedEmail(t *testing.T) {
+	t.Run("verified", func(t *testing.T) {
+		s, provider := newOIDCTestServer(t)
+		alice := s.signup(t)
+		if _, err := s.DB.Exec(`UPDATE users SET email_verified_at = NOW() WHERE id = $1`, alice.User.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{
+			Subject:       "sub-" + randomSuffix(t),
+			Email:         alice.User.Email,
+			EmailVerified: true,
+		})
+		status, raw := oidcCallback(t, s, state, code)
+		expectStatus(t, "callback", status, raw, http.StatusOK)
+		var login testAccount
+		if err := json.Unmarshal(raw, &login); err != nil {
+			t.Fatal(err)
+		}
+		if login.User.ID != alice.User.ID {
+			t.Fatalf("logged in as user %d, want %d", login.User.ID, alice.User.ID)
+		}
+		if n := countIdentities(t, s, alice.User.ID); n != 1 {
+			t.Fatalf("alice has %d identities, want 1", n)
+		}
+	})
+
+	t.Run("not verified by the provider", func(t *testing.T) {
+		s, provider := newOIDCTestServer(t)
+		alice := s.signup(t)
+		if _, err := s.DB.Exec(`UPDATE users SET email_verified_at = NOW() WHERE id = $1`, alice.User.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, claim := range []interface{}{false, "false", nil} {
+			state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{
+				Subject:       "sub-" + randomSuffix(t),
+				Email:         alice.User.Email,
+				EmailVerified: claim,
+			})
+			status, raw := oidcCallback(t, s, state, code)
+			expectStatus(t, "callback", status, raw, http.StatusCreated)
+			var login testAccount
+			if err := json.Unmarshal(raw, &login); err != nil {
+				t.Fatal(err)
+			}
+			if login.User.ID == alice.User.ID {
+				t.Fatalf("email_verified=%v logged into the existing account", claim)
+			}
+		}
+		if n := countIdentities(t, s, alice.User.ID); n != 0 {
+			t.Fatalf("alice has %d identities, want 0", n)
+		}
+	})
+
+	t.Run("not verified locally", func(t *testing.T) {
+		s, provider := newOIDCTestServer(t)
+		alice := s.signup(t)
+
+		state, code := startOIDC(t, s, provider, "", testutil.OIDCUser{
+			Subject:       "sub-" + randomSuffix(t),
+			Email:         alice.User.Email,
+			EmailVerified: true,
+		})
+		status, raw := oidcCallback(t, s, state, code)
+		expectStatus(t, "callback", status, raw, http.StatusConflict)
+		if n := countIdentities(t, s, alice.User.ID); n != 0 {
+			t.Fatalf("alice has %d identities, want 0", n)
+		}
+	})
+}
+
+func TestOIDCLinkFromAccount(t *testing.T) {
+	s, provider := newOIDCTestServer(t)
+	alice := s.signup(t)
+	user := testutil.OIDCUser{Subject: "sub-" + randomSuffix(t), Email: "other@example.com", EmailVerified: false}
+
+	state, code := startOIDC(t, s, provider, alice.Token, user)
+	status, raw := oidcCallback(t, s, state, code)
+	expectStatus(t, "link", status, raw, http.StatusCreated)
+
+	// The linked provider account now logs in to alice.
+	state, code = startOIDC(t, s, provider, "", user)
+	status, raw = oidcCallback(t, s, state, code)
+	expectStatus(t, "login", status, raw, http.StatusOK)
+	var login testAccount
+	if err := json.Unmarshal(raw, &login); err != nil {
+		t.Fatal(err)
+	}
+	if login.User.ID != alice.User.ID {
+		t.Fatalf("logged in as user %d, want %d", login.User.ID, alice.User.ID)
+	}
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+type passkeyBeginResponse struct {
+	ChallengeID string      `json:"challenge_id"`
+	Options     interface{} `json:"options"`
+}
+
+type passkeyRegisterRequest struct {
+	ChallengeID string          `json:"challenge_id"`
+	Name        string          `json:"name"`
+	Credential  json.RawMessage `json:"credential"`
+}
+
+type passkeyLoginRequest struct {
+	ChallengeID string          `json:"challenge_id"`
+	Credential  json.RawMessage `json:"credential"`
+}
+
+type passkeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type passkeyListResponse struct {
+	Passkeys []passkeyResponse `json:"passkeys"`
+}
+
+// passkeyUser adapts a ChainHub account to the webauthn.User interface.
+type passkeyUser struct {
+	user        models.User
+	handle      []byte
+	credentials []webauthn.Credential
+}
+
+func (u passkeyUser) WebAuthnID() []byte                         { return u.handle }
+func (u passkeyUser) WebAuthnName() string                       { return accountLabel(u.user) }
+func (u passkeyUser) WebAuthnDisplayName() string                { return accountLabel(u.user) }
+func (u passkeyUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+func (h *Handler) BeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	pu, err := h.loadPasskeyUser(userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	creation, session, err := h.WebAuthn.BeginRegistration(
+		pu,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(pu.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to start registration")
+		return
+	}
+
+	challengeID, err := h.storeWebAuthnSession(sql.NullInt64{Int64: userID, Valid: true}, ceremonyRegistration, session)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store challenge")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, passkeyBeginResponse{ChallengeID: challengeID, Options: creation})
+}
+
+func (h *Handler) FinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req passkeyRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.ChallengeID == "" || len(req.Credential) == 0 {
+		writeError(w, http.StatusBadRequest, "challenge_id and credential are required")
+		return
+	}
+
+	session, err := h.consumeWebAuthnSession(req.ChallengeID, ceremonyRegistration, sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid or expired challenge")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load challenge")
+		return
+	}
+
+	pu, err := h.loadPasskeyUser(userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid credential")
+		return
+	}
+
+	credential, err := h.WebAuthn.CreateCredential(pu, session, parsed)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "credential verification failed")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	passkey, err := repo.CreatePasskey(h.DB, models.Passkey{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "passkey already registered")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to store passkey")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newPasskeyResponse(passkey))
+}
+
+func (h *Handler) ListPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	passkeys, err := repo.ListPasskeysByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load passkeys")
+		return
+	}
+
+	respPasskeys := make([]passkeyResponse, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		respPasskeys = append(respPasskeys, newPasskeyResponse(passkey))
+	}
+
+	writeJSON(w, http.StatusOK, passkeyListResponse{Passkeys: respPasskeys})
+}
+
+func (h *Handler) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	passkeyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || passkeyID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid passkey id")
+		return
+	}
+
+	passkeys, err := repo.ListPasskeysByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load passkeys")
+		return
+	}
+	found := false
+	for _, passkey := range passkeys {
+		if passkey.ID == passkeyID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "passkey not found")
+		return
+	}
+
+	if err := repo.DeletePasskeyByIDAndUser(h.DB, passkeyID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusConflict, "cannot remove the only login method")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete passkey")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+func (h *Handler) BeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, session, err := h.WebAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to start login")
+		return
+	}
+
+	challengeID, err := h.storeWebAuthnSession(sql.NullInt64{}, ceremonyLogin, session)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store challenge")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, passkeyBeginResponse{ChallengeID: challengeID, Options: assertion})
+}
+
+func (h *Handler) FinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var req passkeyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.ChallengeID == "" || len(req.Credential) == 0 {
+		writeError(w, http.StatusBadRequest, "challenge_id and credential are required")
+		return
+	}
+
+	session, err := h.consumeWebAuthnSession(req.ChallengeID, ceremonyLogin, sql.NullInt64{})
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusUnauthorized, "invalid or expired challenge")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load challenge")
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid credential")
+		return
+	}
+
+	var loggedIn passkeyUser
+	lookup := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := repo.GetUserIDByWebAuthnHandle(h.DB, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		loggedIn, err = h.loadPasskeyUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		return loggedIn, nil
+	}
+
+	_, credential, err := h.WebAuthn.ValidatePasskeyLogin(lookup, session, parsed)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "passkey verification failed")
+		return
+	}
+
+	// A counter that did not advance means the key may have been cloned.
+	if credential.Authenticator.CloneWarning {
+		writeError(w, http.StatusUnauthorized, "passkey verification failed")
+		return
+	}
+
+	if err := repo.RecordPasskeyUse(h.DB, credential.ID, int64(credential.Authenticator.SignCount), credential.Flags.BackupState); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update passkey")
+		return
+	}
+
+	// A user-verified passkey already proves two factors, so TOTP is not asked for.
+	h.writeAuthResponse(w, r, http.StatusOK, loggedIn.user, 0)
+}
+
+func (h *Handler) loadPasskeyUser(userID int64) (passkeyUser, error) {
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+
+	newHandle := make([]byte, 64)
+	if _, err := rand.Read(newHandle); err != nil {
+		return passkeyUser{}, err
+	}
+	handle, err := repo.GetOrCreateWebAuthnHandle(h.DB, userID, newHandle)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+
+	passkeys, err := repo.ListPasskeysByUser(h.DB, userID)
+	if err != nil {
+		return passkeyUser{}, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		var transports []protocol.AuthenticatorTransport
+		for _, t := range strings.Split(passkey.Transports, ",") {
+			if t != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: uint32(passkey.SignCount),
+			},
+		})
+	}
+
+	return passkeyUser{user: user, handle: handle, credentials: credentials}, nil
+}
+
+func (h *Handler) storeWebAuthnSession(userID sql.NullInt64, ceremony string, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := services.GenerateNonce()
+	if err != nil {
+		return "", err
+	}
+
+	err = repo.CreateWebAuthnChallenge(h.DB, models.WebAuthnChallenge{
+		ID:          id,
+		UserID:      userID,
+		Ceremony:    ceremony,
+		SessionData: data,
+		ExpiresAt:   time.Now().Add(h.Config.WebAuthnTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (h *Handler) consumeWebAuthnSession(id, ceremony string, userID sql.NullInt64) (webauthn.SessionData, error) {
+	challenge, err := repo.ConsumeWebAuthnChallenge(h.DB, id, ceremony, userID)
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(challenge.SessionData, &session); err != nil {
+		return webauthn.SessionData{}, err
+	}
+	return session, nil
+}
+
+func newPasskeyResponse(passkey models.Passkey) passkeyResponse {
+	resp := passkeyResponse{
+		ID:        passkey.ID,
+		Name:      passkey.Name,
+		CreatedAt: passkey.CreatedAt,
+	}
+	if passkey.LastUsedAt.Valid {
+		resp.LastUsedAt = &passkey.LastUsedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,184 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"chainhub-api/internal/testutil"
+)
+
+type passkeyBegin struct {
+	ChallengeID string          `json:"challenge_id"`
+	Options     json.RawMessage `json:"options"`
+}
+
+func beginPasskey(t *testing.T, s *testServer, path, token string) (string, testutil.CeremonyOptions) {
+	t.Helper()
+
+	var begin passkeyBegin
+	status, raw := s.do(t, http.MethodPost, path, token, nil)
+	expectStatus(t, path, status, raw, http.StatusOK)
+	if err := json.Unmarshal(raw, &begin); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := testutil.ParseCeremonyOptions(begin.Options)
+	if err != nil {
+		t.Fatalf("%s options: %v", path, err)
+	}
+	return begin.ChallengeID, opts
+}
+
+// registerPasskey runs the registration ceremony with a new software passkey
+// and returns it with its ID on the server.
+func registerPasskey(t *testing.T, s *testServer, token string) (*testutil.Passkey, int64) {
+	t.Helper()
+
+	challengeID, opts := beginPasskey(t, s, "/me/passkeys/register/begin", token)
+	passkey, err := testutil.NewPasskey(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passkey, finishRegistration(t, s, token, challengeID, opts, passkey, http.StatusCreated)
+}
+
+func finishRegistration(t *testing.T, s *testServer, token, challengeID string, opts testutil.CeremonyOptions, passkey *testutil.Passkey, want int) int64 {
+	t.Helper()
+
+	credential, err := passkey.Attest(testFrontendURL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	status, raw := s.do(t, http.MethodPost, "/me/passkeys/register/finish", token, map[string]interface{}{
+		"challenge_id": challengeID,
+		"name":         "Test key",
+		"credential":   json.RawMessage(credential),
+	})
+	expectStatus(t, "finish registration", status, raw, want)
+	if status == http.StatusCreated {
+		if err := json.Unmarshal(raw, &created); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return created.ID
+}
+
+// passkeyLogin runs the login ceremony, with the assertion claiming
+// userHandle, and returns the status and body of the finish request.
+func passkeyLogin(t *testing.T, s *testServer, passkey *testutil.Passkey, userHandle []byte) (int, []byte) {
+	t.Helper()
+
+	challengeID, opts := beginPasskey(t, s, "/auth/passkey/login/begin", "")
+	credential, err := passkey.AssertAs(testFrontendURL, opts, userHandle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.do(t, http.MethodPost, "/auth/passkey/login/finish", "", map[string]interface{}{
+		"challenge_id": challengeID,
+		"credential":   json.RawMessage(credential),
+	})
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+
+	passkey, _ := registerPasskey(t, s, alice.Token)
+
+	status, raw := passkeyLogin(t, s, passkey, passkey.UserHandle)
+	expectStatus(t, "passkey login", status, raw, http.StatusOK)
+	var login testAccount
+	if err := json.Unmarshal(raw, &login); err != nil {
+		t.Fatal(err)
+	}
+	if login.Token == "" || login.User.ID != alice.User.ID {
+		t.Fatalf("logged in as %d with token %q, want user %d", login.User.ID, login.Token, alice.User.ID)
+	}
+
+	var me testAccount
+	if status := s.doJSON(t, http.MethodGet, "/me", login.Token, nil, &me.User); status != http.StatusOK || me.User.ID != alice.User.ID {
+		t.Fatalf("GET /me with passkey token: %d, user %d", status, me.User.ID)
+	}
+}
+
+// A signature counter that does not advance means the private key may have
+// been copied; the login is refused.
+func TestPasskeyLoginRejectsCloneWarning(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	passkey, _ := registerPasskey(t, s, alice.Token)
+
+	for i := 0; i < 2; i++ {
+		status, raw := passkeyLogin(t, s, passkey, passkey.UserHandle)
+		expectStatus(t, "passkey login", status, raw, http.StatusOK)
+	}
+
+	passkey.SignCount = 0 // the copy's counter lags behind the original's
+	status, raw := passkeyLogin(t, s, passkey, passkey.UserHandle)
+	expectStatus(t, "login with a replayed counter", status, raw, http.StatusUnauthorized)
+
+	var stored int64
+	if err := s.DB.QueryRow(
+		`SELECT sign_count FROM webauthn_credentials WHERE credential_id = $1`,
+		passkey.ID,
+	).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 {
+		t.Fatalf("stored sign count = %d after a refused login, want 2", stored)
+	}
+}
+
+func TestPasskeyOwnedByAnotherUser(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	bob := s.signup(t)
+	alicesKey, alicesKeyID := registerPasskey(t, s, alice.Token)
+	bobsKey, _ := registerPasskey(t, s, bob.Token)
+
+	t.Run("login claiming another user's handle", func(t *testing.T) {
+		status, raw := passkeyLogin(t, s, alicesKey, bobsKey.UserHandle)
+		expectStatus(t, "login", status, raw, http.StatusUnauthorized)
+	})
+
+	t.Run("registering the same credential again", func(t *testing.T) {
+		challengeID, opts := beginPasskey(t, s, "/me/passkeys/register/begin", bob.Token)
+		stolen := *alicesKey
+		stolen.UserHandle = opts.UserID
+		finishRegistration(t, s, bob.Token, challengeID, opts, &stolen, http.StatusConflict)
+	})
+
+	t.Run("deleting", func(t *testing.T) {
+		path := "/me/passkeys/" + strconv.FormatInt(alicesKeyID, 10)
+		status, raw := s.do(t, http.MethodDelete, path, bob.Token, nil)
+		expectStatus(t, "delete another user's passkey", status, raw, http.StatusNotFound)
+
+		status, raw = passkeyLogin(t, s, alicesKey, alicesKey.UserHandle)
+		expectStatus(t, "login after the refused delete", status, raw, http.StatusOK)
+	})
+}
+
+func TestDeletePasskeyKeepsLastLoginMethod(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	first, firstID := registerPasskey(t, s, alice.Token)
+	_, secondID := registerPasskey(t, s, alice.Token)
+
+	// Leave the passkeys as the only way in.
+	if _, err := s.DB.Exec(`UPDATE users SET password_hash = NULL WHERE id = $1`, alice.User.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	status, raw := s.do(t, http.MethodDelete, "/me/passkeys/"+strconv.FormatInt(secondID, 10), alice.Token, nil)
+	expectStatus(t, "delete one of two passkeys", status, raw, http.StatusOK)
+
+	status, raw = s.do(t, http.MethodDelete, "/me/passkeys/"+strconv.FormatInt(firstID, 10), alice.Token, nil)
+	expectStatus(t, "delete the last passkey", status, raw, http.StatusConflict)
+
+	status, raw = passkeyLogin(t, s, first, first.UserHandle)
+	expectStatus(t, "login with the kept passkey", status, raw, http.StatusOK)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+)
+
+// checkNewPassword writes a validation error and reports false unless password
+// meets the password policy. userInputs are the account's email and username.
+func (h *Handler) checkNewPassword(w http.ResponseWriter, password string, userInputs ...string) bool {
+	problems := h.Passwords.Check(password, userInputs...)
+	if len(problems) == 0 {
+		return true
+	}
+
+	errs := make([]fieldError, 0, len(problems))
+	for _, p := range problems {
+		errs = append(errs, fieldError{Field: "password", Code: p.Code, Message: p.Message})
+	}
+	writeValidationErrors(w, errs)
+	return false
+}
+
+// verifyPassword reports whether password is user's. A hash made with another
+// algorithm or older work factors than configured is replaced on the way, so
+// stored hashes follow the configuration as users log in.
+func (h *Handler) verifyPassword(user models.User, password string) bool {
+	if !user.PasswordHash.Valid {
+		return false
+	}
+
+	ok, needsRehash, err := h.Hasher.Verify(user.PasswordHash.String, password)
+	if err != nil {
+		log.Printf("password: failed to verify hash of user %d: %v", user.ID, err)
+		return false
+	}
+	if ok && needsRehash {
+		hash, err := h.Hasher.Hash(password)
+		if err == nil {
+			err = repo.ReplacePasswordHash(h.DB, user.ID, user.PasswordHash.String, hash)
+		}
+		if err != nil && !errors.Is(err, repo.ErrNotFound) {
+			log.Printf("password: failed to upgrade hash of user %d: %v", user.ID, err)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/mailer"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+const forgotPasswordMessage = "if an account exists for that email, a reset link has been sent"
+
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeError(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	// The response never depends on whether the account exists, and mail is
+	// sent in the background so response timing does not leak it either.
+	user, err := repo.GetUserByEmail(h.DB, email)
+	if err == nil {
+		token, tokenHash, err := services.GenerateOpaqueToken()
+		if err == nil {
+			err = repo.CreatePasswordResetToken(h.DB, user.ID, tokenHash, time.Now().Add(h.Config.PasswordResetTTL))
+		}
+		if err != nil {
+			log.Printf("password reset: failed to create token for user %d: %v", user.ID, err)
+		} else {
+			go h.sendMail(mailer.Message{
+				To:      user.Email.String,
+				Subject: "Reset your ChainHub password",
+				Body:    passwordResetBody(h.frontendLink("/reset-password", token), h.Config.PasswordResetTTL),
+			})
+		}
+	} else if !errors.Is(err, repo.ErrNotFound) {
+		log.Printf("password reset: failed to load user: %v", err)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": forgotPasswordMessage})
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	token := strings.TrimSpace(req.Token)
+	if token == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "token and password are required")
+		return
+	}
+
+	user, err := repo.GetPasswordResetUser(h.DB, services.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid or expired reset token")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load reset token")
+		return
+	}
+	if !h.checkNewPassword(w, req.Password, user.Email.String, user.Username.String) {
+		return
+	}
+
+	hash, err := h.Hasher.Hash(req.Password)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	_, sessionIDs, err := repo.ResetPassword(h.DB, services.HashToken(token), hash)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid or expired reset token")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+	for _, id := range sessionIDs {
+		h.Sessions.MarkRevoked(id)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"reset": true})
+}
+
+func (h *Handler) sendMail(msg mailer.Message) {
+	if err := h.Mailer.Send(msg); err != nil {
+		log.Printf("mailer: failed to send %q: %v", msg.Subject, err)
+	}
+}
+
+// frontendLink builds a link into the web app carrying a one-time token.
+func (h *Handler) frontendLink(path, token string) string {
+	return strings.TrimSuffix(h.Config.FrontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func passwordResetBody(link string, ttl time.Duration) string {
+	return fmt.Sprintf(
+		"Someone asked to reset the password for your ChainHub account.\n\n"+
+			"Use this link to choose a new password. It expires in %s and can only be used once:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+		ttl,
+		link,
+	)
+}
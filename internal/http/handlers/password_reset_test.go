@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+// A reset locks out whoever knew the old password: sessions and personal
+// access tokens both stop working.
+func TestResetPasswordRevokesSessionsAndAPITokens(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+
+	var pat struct {
+		Token string `json:"token"`
+	}
+	if status := s.doJSON(t, http.MethodPost, "/me/tokens", alice.Token, map[string]interface{}{
+		"name":   "ci",
+		"scopes": []string{"tree:read"},
+	}, &pat); status != http.StatusCreated {
+		t.Fatalf("create token: %d", status)
+	}
+	status, raw := s.do(t, http.MethodGet, "/trees", pat.Token, nil)
+	expectStatus(t, "token before reset", status, raw, http.StatusOK)
+
+	resetToken, resetHash, err := services.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreatePasswordResetToken(s.DB, alice.User.ID, resetHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	status, raw = s.do(t, http.MethodPost, "/auth/password/reset", "", map[string]string{
+		"token":    resetToken,
+		"password": "another horse battery staple 43",
+	})
+	expectStatus(t, "reset", status, raw, http.StatusOK)
+
+	status, raw = s.do(t, http.MethodGet, "/trees", pat.Token, nil)
+	expectStatus(t, "token after reset", status, raw, http.StatusUnauthorized)
+	status, raw = s.do(t, http.MethodGet, "/me", alice.Token, nil)
+	expectStatus(t, "session after reset", status, raw, http.StatusUnauthorized)
+}
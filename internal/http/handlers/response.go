@@ -14,3 +14,20 @@ func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, map[string]string{"error": message})
 }
+
+// fieldError is one reason a request field was rejected. Code is stable for
+// clients to match on; Message is for people.
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeValidationErrors responds 400 with every problem found. "error" holds
+// the first message, for clients that only show that.
+func writeValidationErrors(w http.ResponseWriter, errs []fieldError) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":   errs[0].Message,
+		"details": errs,
+	})
+}
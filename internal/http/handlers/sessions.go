@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type sessionResponse struct {
+	ID         int64     `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+type sessionListResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	currentID, _ := middleware.GetSessionID(r.Context())
+
+	sessions, err := repo.ListActiveSessionsByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load sessions")
+		return
+	}
+
+	respSessions := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		respSessions = append(respSessions, sessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, sessionListResponse{Sessions: respSessions})
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid session id")
+		return
+	}
+
+	if err := repo.RevokeSessionByIDAndUser(h.DB, sessionID, userID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to revoke session")
+		return
+	}
+	h.Sessions.MarkRevoked(sessionID)
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// clientIP is the address sessions record and IP throttles key on. Behind a
+// trusted proxy it comes from X-Forwarded-For; see middleware.ClientIP.
+func clientIP(r *http.Request) string {
+	if ip := middleware.GetClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// describeDevice turns a user agent into a short label for the sessions list.
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "Unknown device"
+	case strings.Contains(ua, "iphone"):
+		return "iPhone"
+	case strings.Contains(ua, "ipad"):
+		return "iPad"
+	case strings.Contains(ua, "android"):
+		return "Android"
+	case strings.Contains(ua, "mac os"):
+		return "Mac"
+	case strings.Contains(ua, "windows"):
+		return "Windows"
+	case strings.Contains(ua, "linux"):
+		return "Linux"
+	default:
+		return "Other"
+	}
+}
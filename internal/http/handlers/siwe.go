@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+type siweNonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type siweVerifyRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+func (h *Handler) SIWENonce(w http.ResponseWriter, r *http.Request) {
+	nonce, err := services.GenerateNonce()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create nonce")
+		return
+	}
+
+	expiresAt := time.Now().Add(h.Config.SIWENonceTTL)
+	if err := repo.CreateSIWENonce(h.DB, nonce, expiresAt); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store nonce")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, siweNonceResponse{
+		Nonce:     nonce,
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
+
+func (h *Handler) SIWEVerify(w http.ResponseWriter, r *http.Request) {
+	var req siweVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.Message == "" || req.Signature == "" {
+		writeError(w, http.StatusBadRequest, "message and signature are required")
+		return
+	}
+
+	msg, err := services.ParseSIWEMessage(req.Message)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = msg.Validate(services.SIWEExpectations{
+		Domain:   h.Config.SIWEDomain,
+		ChainIDs: h.Config.SIWEChainIDs,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	signer, err := services.RecoverPersonalSignAddress(req.Message, req.Signature)
+	if err != nil || signer != msg.Address {
+		writeError(w, http.StatusUnauthorized, "signature does not match address")
+		return
+	}
+
+	if err := repo.ConsumeSIWENonce(h.DB, msg.Nonce); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusUnauthorized, "invalid or expired nonce")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to verify nonce")
+		return
+	}
+
+	user, treeID, status, err := h.findOrCreateWalletUser(msg.Address)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.completeLogin(w, r, status, user, treeID)
+}
+
+// findOrCreateWalletUser logs in the owner of address, creating an account and
+// tree on first sign-in. The returned status mirrors Signup/Login.
+func (h *Handler) findOrCreateWalletUser(address string) (models.User, int64, int, error) {
+	user, err := repo.GetUserByWalletAddress(h.DB, address)
+	if err == nil {
+		return user, 0, http.StatusOK, nil
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		return models.User{}, 0, 0, errors.New("failed to load user")
+	}
+
+	user, err = repo.CreateUserWithWallet(h.DB, address)
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			// Lost a race with a concurrent first sign-in for the same wallet.
+			user, err = repo.GetUserByWalletAddress(h.DB, address)
+			if err == nil {
+				return user, 0, http.StatusOK, nil
+			}
+		}
+		return models.User{}, 0, 0, errors.New("failed to create user")
+	}
+
+	treeTitle := fmt.Sprintf("%s's Link Tree", shortAddress(address))
+	tree, err := h.createDefaultTree(user.ID, treeTitle, "")
+	if err != nil {
+		return models.User{}, 0, 0, errors.New("failed to create tree")
+	}
+
+	return user, tree.ID, http.StatusCreated, nil
+}
+
+func shortAddress(address string) string {
+	if len(address) < 10 {
+		return address
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/services"
+)
+
+type themeRequest struct {
+	Preset    string          `json:"preset"`
+	Overrides json.RawMessage `json:"overrides"`
+}
+
+// ownedThemeResponse is a tree's theme as its owner edits it: what was chosen
+// and what it resolves to.
+type ownedThemeResponse struct {
+	Preset    string          `json:"preset"`
+	Overrides json.RawMessage `json:"overrides"`
+	Resolved  services.Theme  `json:"resolved"`
+}
+
+type themeCatalogResponse struct {
+	Presets      []services.ThemePreset `json:"presets"`
+	Fonts        []string               `json:"fonts"`
+	ButtonShapes []string               `json:"button_shapes"`
+	ButtonStyles []string               `json:"button_styles"`
+	Backgrounds  []string               `json:"backgrounds"`
+	Layouts      []string               `json:"layouts"`
+	MinContrast  map[string]float64     `json:"min_contrast"`
+}
+
+// ListThemes returns the theme presets and the values overrides may use.
+func (h *Handler) ListThemes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, themeCatalogResponse{
+		Presets:      services.ThemePresets,
+		Fonts:        services.ThemeFontFamilies,
+		ButtonShapes: services.ThemeButtonShapes,
+		ButtonStyles: services.ThemeButtonStyles,
+		Backgrounds:  services.ThemeBackgrounds,
+		Layouts:      services.ThemeLayouts,
+		MinContrast: map[string]float64{
+			"text":    services.ThemeTextContrast,
+			"outline": services.ThemeOutlineContrast,
+		},
+	})
+}
+
+// applyTreeTheme validates a new theme and sets it on tree. An empty preset
+// means the default one. The overrides are stored re-encoded, so nulls and
+// formatting from the request do not end up in the database.
+func applyTreeTheme(tree *models.Tree, req themeRequest) []fieldError {
+	preset := req.Preset
+	if preset == "" {
+		preset = services.DefaultThemePreset
+	}
+
+	overrides, err := services.ParseThemeOverrides(req.Overrides)
+	if err != nil {
+		return []fieldError{{Field: "theme.overrides", Code: services.ThemeProblemInvalid, Message: "invalid theme overrides: " + err.Error()}}
+	}
+	if _, problems := services.ResolveTheme(preset, overrides); len(problems) > 0 {
+		errs := make([]fieldError, 0, len(problems))
+		for _, p := range problems {
+			errs = append(errs, fieldError{Field: "theme." + p.Field, Code: p.Code, Message: p.Message})
+		}
+		return errs
+	}
+
+	stored, err := json.Marshal(overrides)
+	if err != nil {
+		return []fieldError{{Field: "theme.overrides", Code: services.ThemeProblemInvalid, Message: "invalid theme overrides"}}
+	}
+	tree.ThemePreset = preset
+	tree.ThemeOverrides = stored
+	return nil
+}
+
+// resolveTreeTheme returns the theme a tree is rendered with. A stored theme
+// that no longer validates, say because its preset was removed, falls back to
+// the default preset rather than breaking the page.
+func resolveTreeTheme(tree models.Tree) services.Theme {
+	overrides, err := services.ParseThemeOverrides(tree.ThemeOverrides)
+	if err != nil {
+		log.Printf("themes: tree %d has invalid overrides, using the default theme: %v", tree.ID, err)
+		return defaultTheme()
+	}
+	theme, problems := services.ResolveTheme(tree.ThemePreset, overrides)
+	if len(problems) > 0 {
+		log.Printf("themes: tree %d has an invalid theme, using the default: %s", tree.ID, problems[0].Message)
+		return defaultTheme()
+	}
+	return theme
+}
+
+func defaultTheme() services.Theme {
+	theme, _ := services.ResolveTheme(services.DefaultThemePreset, services.ThemeOverrides{})
+	return theme
+}
+
+func newOwnedThemeResponse(tree models.Tree) ownedThemeResponse {
+	overrides := json.RawMessage(tree.ThemeOverrides)
+	if len(overrides) == 0 {
+		overrides = json.RawMessage("{}")
+	}
+	return ownedThemeResponse{
+		Preset:    tree.ThemePreset,
+		Overrides: overrides,
+		Resolved:  resolveTreeTheme(tree),
+	}
+}
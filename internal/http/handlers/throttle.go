@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountThrottleKey is the lockout key for a known account. Failed logins and
+// second-factor attempts for the same account share it.
+func accountThrottleKey(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+// unknownAccountThrottleKey throttles guesses at identifiers that match no
+// account, so they behave like real ones.
+func unknownAccountThrottleKey(identifier string) string {
+	return "name:" + strings.ToLower(identifier)
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration, message string) {
+	setRetryAfter(w, wait)
+	writeError(w, http.StatusTooManyRequests, message)
+}
+
+// checkThrottled writes a 429 and reports true if any of the waits is still
+// running. The longest wait wins.
+func checkThrottled(w http.ResponseWriter, waits ...time.Duration) bool {
+	var longest time.Duration
+	for _, wait := range waits {
+		if wait > longest {
+			longest = wait
+		}
+	}
+	if longest <= 0 {
+		return false
+	}
+	writeRetryAfter(w, longest, "too many attempts, try again later")
+	return true
+}
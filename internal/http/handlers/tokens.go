@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueTokens opens a session for the client making r and returns a
+// short-lived access token plus the first refresh token of a new family.
+func (h *Handler) issueTokens(r *http.Request, user models.User) (tokenResponse, error) {
+	userAgent := r.UserAgent()
+	session, err := repo.CreateSession(h.DB, user.ID, describeDevice(userAgent), clientIP(r), userAgent)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	accessToken, err := services.GenerateJWT(h.Keys, user.ID, session.ID, user.Role, h.Config.AccessTokenTTL)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	familyID, err := services.GenerateTokenFamily()
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	refreshToken, refreshHash, err := services.GenerateOpaqueToken()
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	expiresAt := time.Now().Add(h.Config.RefreshTokenTTL)
+	if _, err := repo.CreateRefreshToken(h.DB, user.ID, session.ID, familyID, refreshHash, expiresAt); err != nil {
+		return tokenResponse{}, err
+	}
+
+	return tokenResponse{Token: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	presented := strings.TrimSpace(req.RefreshToken)
+	if presented == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	refreshToken, refreshHash, err := services.GenerateOpaqueToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+
+	expiresAt := time.Now().Add(h.Config.RefreshTokenTTL)
+	rotated, err := repo.RotateRefreshToken(h.DB, services.HashToken(presented), refreshHash, expiresAt)
+	if err != nil {
+		if errors.Is(err, repo.ErrTokenReused) {
+			h.Sessions.MarkRevoked(rotated.SessionID)
+			writeError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to refresh token")
+		return
+	}
+
+	// The role may have changed since the session began.
+	user, err := repo.GetUserByID(h.DB, rotated.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	if user.SuspendedAt.Valid {
+		writeError(w, http.StatusForbidden, "account suspended")
+		return
+	}
+
+	accessToken, err := services.GenerateJWT(h.Keys, rotated.UserID, rotated.SessionID, user.Role, h.Config.AccessTokenTTL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	presented := strings.TrimSpace(req.RefreshToken)
+	if presented == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	sessionID, err := repo.RevokeSessionByRefreshToken(h.DB, services.HashToken(presented))
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "failed to revoke token")
+		return
+	}
+	if err == nil {
+		h.Sessions.MarkRevoked(sessionID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"revoked": true})
+}
@@ -3,8 +3,12 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"chainhub-api/internal/models"
 	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -12,12 +16,16 @@ import (
 type treeResponse struct {
 	ID    int64  `json:"id"`
 	Username  string `json:"username"`
+	Slug  string `json:"slug"`
 	Title string `json:"title"`
+	treeProfile
+	Theme services.Theme `json:"theme"`
 	Links []linkResponse `json:"links"`
 }
 
 type linkResponse struct {
 	ID       int64  `json:"id"`
+	TreeID   int64  `json:"tree_id"`
 	Title    string `json:"title"`
 	URL      string `json:"url"`
 	Position int    `json:"position"`
@@ -33,6 +41,11 @@ func (h *Handler) GetTreeByUsername(w http.ResponseWriter, r *http.Request) {
 	tree, err := repo.GetTreeByUsername(h.DB, username)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
+			// Renamed accounts keep their old URL for a while.
+			if current, err := repo.GetUsernameRedirect(h.DB, username); err == nil {
+				http.Redirect(w, r, "/tree/"+url.PathEscape(current), http.StatusMovedPermanently)
+				return
+			}
 			writeError(w, http.StatusNotFound, "tree not found")
 			return
 		}
@@ -40,16 +53,67 @@ func (h *Handler) GetTreeByUsername(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	links, err := repo.ListActiveLinksByTreeID(h.DB, tree.ID)
+	h.writePublishedTree(w, tree)
+}
+
+// GetTreeBySlug serves any tree, default or not, by its slug.
+func (h *Handler) GetTreeBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(chi.URLParam(r, "slug"))
+	if slug == "" {
+		writeError(w, http.StatusBadRequest, "slug is required")
+		return
+	}
+
+	tree, err := repo.GetTreeBySlug(h.DB, slug)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+
+	h.writePublishedTree(w, tree)
+}
+
+func (h *Handler) writePublishedTree(w http.ResponseWriter, tree models.Tree) {
+	snapshot, err := repo.GetPublishedTree(h.DB, tree.ID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+
+	h.writePublicTree(w, tree, snapshot)
+}
+
+// writePublicTree renders snapshot as tree's public page. The published
+// version and the owner's preview of the draft both go through here, so the
+// preview shows exactly what publishing would.
+func (h *Handler) writePublicTree(w http.ResponseWriter, tree models.Tree, snapshot models.TreeSnapshot) {
+	owner, err := repo.GetUserByID(h.DB, tree.UserID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to load links")
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+	if owner.SuspendedAt.Valid {
+		writeError(w, http.StatusGone, "tree is no longer available")
 		return
 	}
 
-	respLinks := make([]linkResponse, 0, len(links))
-	for _, link := range links {
+	tree = treeFromSnapshot(tree, snapshot)
+	respLinks := make([]linkResponse, 0, len(snapshot.Links))
+	for _, link := range snapshot.Links {
+		if !link.IsActive {
+			continue
+		}
 		respLinks = append(respLinks, linkResponse{
 			ID:       link.ID,
+			TreeID:   tree.ID,
 			Title:    link.Title,
 			URL:      link.URL,
 			Position: link.Position,
@@ -58,8 +122,21 @@ func (h *Handler) GetTreeByUsername(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, treeResponse{
 		ID:    tree.ID,
-		Username:  username,
+		Username:  owner.Username.String,
+		Slug:  tree.Slug,
 		Title: tree.Title,
+		treeProfile: newTreeProfile(tree),
+		Theme: resolveTreeTheme(tree),
 		Links: respLinks,
 	})
 }
+
+func newLinkResponse(link models.Link) linkResponse {
+	return linkResponse{
+		ID:       link.ID,
+		TreeID:   link.TreeID,
+		Title:    link.Title,
+		URL:      link.URL,
+		Position: link.Position,
+	}
+}
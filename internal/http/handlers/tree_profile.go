@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/services"
+)
+
+type socialRequest struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+type socialResponse struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// treeProfile is the part of a tree shown above its links. It is embedded in
+// both the public and the owner's view of a tree.
+type treeProfile struct {
+	DisplayName string           `json:"display_name"`
+	Bio         string           `json:"bio"`
+	BioHTML     string           `json:"bio_html"`
+	AvatarURL   string           `json:"avatar_url"`
+	Socials     []socialResponse `json:"socials"`
+}
+
+// applyTreeProfile validates the profile fields present in req and sets them
+// on tree. It returns every problem found; tree is only meaningful if there
+// are none.
+func applyTreeProfile(tree *models.Tree, req updateTreeRequest) []fieldError {
+	var errs []fieldError
+
+	if req.DisplayName != nil {
+		name, err := services.NormalizeDisplayName(*req.DisplayName)
+		if err != nil {
+			errs = append(errs, fieldError{Field: "display_name", Code: "invalid", Message: err.Error()})
+		}
+		tree.DisplayName = name
+	}
+	if req.Bio != nil {
+		bio, err := services.SanitizeBio(*req.Bio)
+		if err != nil {
+			errs = append(errs, fieldError{Field: "bio", Code: "invalid", Message: err.Error()})
+		}
+		tree.Bio = bio
+	}
+	if req.AvatarURL != nil {
+		avatar, err := services.NormalizeAvatarURL(*req.AvatarURL)
+		if err != nil {
+			errs = append(errs, fieldError{Field: "avatar_url", Code: "invalid_url", Message: err.Error()})
+		}
+		tree.AvatarURL = avatar
+	}
+	if req.Socials != nil {
+		socials, socialErrs := normalizeSocials(*req.Socials)
+		errs = append(errs, socialErrs...)
+		tree.Socials = socials
+	}
+	return errs
+}
+
+// normalizeSocials checks a full replacement list of social icons, kept in
+// the order given.
+func normalizeSocials(reqs []socialRequest) ([]models.TreeSocial, []fieldError) {
+	if len(reqs) > services.SocialLinksMax {
+		return nil, []fieldError{{
+			Field:   "socials",
+			Code:    "too_many",
+			Message: "at most " + strconv.Itoa(services.SocialLinksMax) + " social links are allowed",
+		}}
+	}
+
+	var errs []fieldError
+	socials := make([]models.TreeSocial, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i, req := range reqs {
+		field := "socials[" + strconv.Itoa(i) + "]"
+		u, err := services.NormalizeSocialURL(req.Platform, req.URL)
+		switch {
+		case errors.Is(err, services.ErrSocialPlatform):
+			errs = append(errs, fieldError{Field: field + ".platform", Code: "unknown_platform", Message: err.Error() + ": " + strconv.Quote(req.Platform)})
+			continue
+		case err != nil:
+			errs = append(errs, fieldError{Field: field + ".url", Code: "invalid_url", Message: err.Error()})
+			continue
+		case seen[u]:
+			errs = append(errs, fieldError{Field: field + ".url", Code: "duplicate", Message: "social link is listed twice"})
+			continue
+		}
+		seen[u] = true
+		socials = append(socials, models.TreeSocial{Platform: req.Platform, URL: u})
+	}
+	return socials, errs
+}
+
+func newTreeProfile(tree models.Tree) treeProfile {
+	socials := make([]socialResponse, 0, len(tree.Socials))
+	for _, s := range tree.Socials {
+		socials = append(socials, socialResponse{Platform: s.Platform, URL: s.URL})
+	}
+	return treeProfile{
+		DisplayName: tree.DisplayName,
+		Bio:         tree.Bio,
+		BioHTML:     services.RenderBio(tree.Bio),
+		AvatarURL:   tree.AvatarURL,
+		Socials:     socials,
+	}
+}
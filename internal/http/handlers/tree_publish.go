@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+)
+
+// PreviewTree renders the draft of one of the user's trees as its public page
+// would show it once published.
+func (h *Handler) PreviewTree(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	draft, err := repo.GetTreeDraft(h.DB, tree)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+
+	h.writePublicTree(w, tree, draft)
+}
+
+// PublishTree makes the draft the public version.
+func (h *Handler) PublishTree(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	published, err := repo.PublishTree(h.DB, tree.ID, tree.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to publish tree")
+		return
+	}
+
+	h.writeOwnedTreeWithDraftState(w, published)
+}
+
+// DiscardTreeDraft puts the tree and its links back as last published.
+func (h *Handler) DiscardTreeDraft(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	restored, err := repo.DiscardTreeDraft(h.DB, tree.ID, tree.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to discard changes")
+		return
+	}
+
+	h.writeOwnedTreeWithDraftState(w, restored)
+}
+
+func (h *Handler) writeOwnedTreeWithDraftState(w http.ResponseWriter, tree models.Tree) {
+	resp := newOwnedTreeResponse(tree)
+	unpublished, err := h.hasUnpublishedChanges(tree)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+	resp.HasUnpublishedChanges = &unpublished
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// hasUnpublishedChanges compares the draft with the published version.
+func (h *Handler) hasUnpublishedChanges(tree models.Tree) (bool, error) {
+	draft, err := repo.GetTreeDraft(h.DB, tree)
+	if err != nil {
+		return false, err
+	}
+	published, err := repo.GetPublishedTree(h.DB, tree.ID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !sameSnapshot(draft, published), nil
+}
+
+// autoPublishTree publishes the tree right away if its owner turned drafts
+// off. It reports the published tree, if it was published. Failures are only
+// logged: the edit itself has been saved and can still be published by hand.
+func (h *Handler) autoPublishTree(treeID, userID int64) (models.Tree, bool) {
+	tree, err := repo.PublishTreeIfAuto(h.DB, treeID, userID)
+	if err != nil {
+		if !errors.Is(err, repo.ErrNotFound) {
+			log.Printf("trees: failed to auto-publish tree %d: %v", treeID, err)
+		}
+		return models.Tree{}, false
+	}
+	return tree, true
+}
+
+// treeFromSnapshot returns tree with its content replaced by snapshot's.
+func treeFromSnapshot(tree models.Tree, snapshot models.TreeSnapshot) models.Tree {
+	tree.Title = snapshot.Title
+	tree.DisplayName = snapshot.DisplayName
+	tree.Bio = snapshot.Bio
+	tree.AvatarURL = snapshot.AvatarURL
+	tree.Socials = snapshot.Socials
+	tree.ThemePreset = snapshot.ThemePreset
+	tree.ThemeOverrides = snapshot.ThemeOverrides
+	return tree
+}
+
+// sameSnapshot compares snapshots as JSON values, since the database does not
+// keep the formatting or key order of the theme overrides.
+func sameSnapshot(a, b models.TreeSnapshot) bool {
+	return services.SameJSON(a, b)
+}
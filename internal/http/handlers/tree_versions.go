@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	treeVersionListDefaultLimit = 20
+	treeVersionListMaxLimit     = 100
+)
+
+type treeVersionSummary struct {
+	Version   int       `json:"version"`
+	Action    string    `json:"action"`
+	AuthorID  *int64    `json:"author_id"`
+	Title     string    `json:"title"`
+	LinkCount int       `json:"link_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type treeVersionListResponse struct {
+	Versions []treeVersionSummary `json:"versions"`
+}
+
+type treeVersionResponse struct {
+	treeVersionSummary
+	Snapshot models.TreeSnapshot `json:"snapshot"`
+}
+
+type treeVersionDiffResponse struct {
+	From int `json:"from"`
+	// To is the version compared against, or null for the current draft.
+	To *int `json:"to"`
+	services.TreeDiff
+}
+
+type restoreTreeVersionRequest struct {
+	Publish *bool `json:"publish"`
+}
+
+// ListTreeVersions lists the tree's published versions, newest first, paged
+// with ?limit= and ?offset=.
+func (h *Handler) ListTreeVersions(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := treeVersionListDefaultLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > treeVersionListMaxLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(treeVersionListMaxLimit))
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if raw := query.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must not be negative")
+			return
+		}
+		offset = n
+	}
+
+	versions, err := repo.ListTreeVersions(h.DB, tree.ID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load versions")
+		return
+	}
+
+	resp := treeVersionListResponse{Versions: make([]treeVersionSummary, 0, len(versions))}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, newTreeVersionSummary(v))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) GetTreeVersion(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+	number, ok := parseTreeVersion(w, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	v, ok := h.loadTreeVersion(w, tree.ID, number)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, treeVersionResponse{
+		treeVersionSummary: newTreeVersionSummary(v),
+		Snapshot:           v.Snapshot,
+	})
+}
+
+// DiffTreeVersions compares two versions of the tree, given as ?from= and
+// ?to=. to defaults to the latest version and may be "draft" for the
+// unpublished working copy; from defaults to the version before to, or the
+// latest version when comparing the draft.
+func (h *Handler) DiffTreeVersions(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	latest, err := repo.LatestTreeVersion(h.DB, tree.ID)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "failed to load versions")
+		return
+	}
+
+	query := r.URL.Query()
+	var resp treeVersionDiffResponse
+	var to models.TreeSnapshot
+	switch raw := query.Get("to"); raw {
+	case "draft":
+		to, err = repo.GetTreeDraft(h.DB, tree)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load tree")
+			return
+		}
+		resp.From = latest
+	default:
+		number := latest
+		if raw != "" {
+			if number, ok = parseTreeVersion(w, raw); !ok {
+				return
+			}
+		}
+		v, ok := h.loadTreeVersion(w, tree.ID, number)
+		if !ok {
+			return
+		}
+		to = v.Snapshot
+		resp.To = &number
+		resp.From = number - 1
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		if resp.From, ok = parseTreeVersion(w, raw); !ok {
+			return
+		}
+	}
+	if resp.From < 1 {
+		writeError(w, http.StatusBadRequest, "nothing to compare with")
+		return
+	}
+	from, ok := h.loadTreeVersion(w, tree.ID, resp.From)
+	if !ok {
+		return
+	}
+
+	resp.TreeDiff = services.DiffTreeSnapshots(from.Snapshot, to)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RestoreTreeVersion puts the tree and its links back as they were in the
+// given version. Unless the body has "publish": false the restored state is
+// published right away, as a new version.
+func (h *Handler) RestoreTreeVersion(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+	number, ok := parseTreeVersion(w, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	var req restoreTreeVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	publish := req.Publish == nil || *req.Publish
+
+	restored, err := repo.RestoreTreeVersion(h.DB, tree.ID, tree.UserID, number, publish)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "version not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to restore version")
+		return
+	}
+	if !publish {
+		if published, ok := h.autoPublishTree(tree.ID, tree.UserID); ok {
+			restored = published
+		}
+	}
+
+	h.writeOwnedTreeWithDraftState(w, restored)
+}
+
+func (h *Handler) loadTreeVersion(w http.ResponseWriter, treeID int64, number int) (models.TreeVersion, bool) {
+	v, err := repo.GetTreeVersion(h.DB, treeID, number)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "version not found")
+			return models.TreeVersion{}, false
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load version")
+		return models.TreeVersion{}, false
+	}
+	return v, true
+}
+
+func parseTreeVersion(w http.ResponseWriter, raw string) (int, bool) {
+	number, err := strconv.Atoi(raw)
+	if err != nil || number < 1 {
+		writeError(w, http.StatusBadRequest, "invalid version")
+		return 0, false
+	}
+	return number, true
+}
+
+func newTreeVersionSummary(v models.TreeVersion) treeVersionSummary {
+	summary := treeVersionSummary{
+		Version:   v.Version,
+		Action:    v.Action,
+		Title:     v.Snapshot.Title,
+		LinkCount: len(v.Snapshot.Links),
+		CreatedAt: v.CreatedAt,
+	}
+	if v.AuthorID.Valid {
+		summary.AuthorID = &v.AuthorID.Int64
+	}
+	return summary
+}
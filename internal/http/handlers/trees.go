@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	treeTitleMaxLength = 100
+	// treeSlugAttempts bounds retries with a new random suffix when a
+	// generated slug is taken.
+	treeSlugAttempts = 5
+)
+
+type createTreeRequest struct {
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+type updateTreeRequest struct {
+	Title       *string          `json:"title"`
+	Slug        *string          `json:"slug"`
+	IsDefault   *bool            `json:"is_default"`
+	AutoPublish *bool            `json:"auto_publish"`
+	DisplayName *string          `json:"display_name"`
+	Bio         *string          `json:"bio"`
+	AvatarURL   *string          `json:"avatar_url"`
+	Socials     *[]socialRequest `json:"socials"`
+	Theme       *themeRequest    `json:"theme"`
+}
+
+type ownedTreeResponse struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Slug      string `json:"slug"`
+	IsDefault bool   `json:"is_default"`
+	treeProfile
+	Theme       ownedThemeResponse `json:"theme"`
+	AutoPublish bool               `json:"auto_publish"`
+	PublishedAt *time.Time         `json:"published_at"`
+	// HasUnpublishedChanges is only filled in where the draft was compared
+	// with the published version.
+	HasUnpublishedChanges *bool          `json:"has_unpublished_changes,omitempty"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	Links                 []linkResponse `json:"links,omitempty"`
+}
+
+type treeListResponse struct {
+	Trees []ownedTreeResponse `json:"trees"`
+}
+
+// CreateTree adds a tree. Without a slug, one is made from the title.
+func (h *Handler) CreateTree(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req createTreeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if msg := validateTreeTitle(title); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if slug != "" {
+		if err := services.ValidateTreeSlug(slug); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	count, err := repo.CountTreesByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create tree")
+		return
+	}
+	if count >= h.Config.MaxTreesPerUser {
+		writeError(w, http.StatusConflict, "tree limit of "+strconv.Itoa(h.Config.MaxTreesPerUser)+" reached")
+		return
+	}
+	var tree models.Tree
+	if slug != "" {
+		tree, err = repo.CreateTree(h.DB, userID, title, slug)
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "slug already in use")
+			return
+		}
+	} else {
+		tree, err = h.createTreeWithGeneratedSlug(userID, title, title)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create tree")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newOwnedTreeResponse(tree))
+}
+
+func (h *Handler) ListTrees(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	trees, err := repo.ListTreesByUser(h.DB, userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load trees")
+		return
+	}
+
+	respTrees := make([]ownedTreeResponse, 0, len(trees))
+	for _, tree := range trees {
+		respTrees = append(respTrees, newOwnedTreeResponse(tree))
+	}
+
+	writeJSON(w, http.StatusOK, treeListResponse{Trees: respTrees})
+}
+
+// GetTree returns the draft of one of the user's trees with all its links,
+// inactive ones included.
+func (h *Handler) GetTree(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := repo.ListLinksByTreeIDAndUser(h.DB, tree.ID, tree.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load links")
+		return
+	}
+
+	unpublished, err := h.hasUnpublishedChanges(tree)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return
+	}
+
+	resp := newOwnedTreeResponse(tree)
+	resp.HasUnpublishedChanges = &unpublished
+	resp.Links = make([]linkResponse, 0, len(links))
+	for _, link := range links {
+		resp.Links = append(resp.Links, newLinkResponse(link))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateTree changes a tree's title, slug, profile, theme or auto-publish
+// setting, or makes it the default tree. Fields left out are kept; socials
+// and theme, when given, replace what was there. Content changes go to the
+// draft; slug and default take effect at once, and the old slug is not
+// redirected.
+func (h *Handler) UpdateTree(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateTreeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	title := tree.Title
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+		if msg := validateTreeTitle(title); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+	slug := tree.Slug
+	if req.Slug != nil {
+		slug = strings.ToLower(strings.TrimSpace(*req.Slug))
+		if err := services.ValidateTreeSlug(slug); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	makeDefault := false
+	if req.IsDefault != nil {
+		if !*req.IsDefault && tree.IsDefault {
+			writeError(w, http.StatusBadRequest, "make another tree the default instead")
+			return
+		}
+		makeDefault = *req.IsDefault && !tree.IsDefault
+	}
+	errs := applyTreeProfile(&tree, req)
+	if req.Theme != nil {
+		errs = append(errs, applyTreeTheme(&tree, *req.Theme)...)
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+	tree.Title = title
+	tree.Slug = slug
+	if req.AutoPublish != nil {
+		tree.AutoPublish = *req.AutoPublish
+	}
+
+	updated, err := repo.UpdateTree(h.DB, tree, makeDefault)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			writeError(w, http.StatusNotFound, "tree not found")
+		case errors.Is(err, repo.ErrDuplicate):
+			writeError(w, http.StatusConflict, "slug already in use")
+		default:
+			writeError(w, http.StatusInternalServerError, "failed to update tree")
+		}
+		return
+	}
+	if published, ok := h.autoPublishTree(updated.ID, updated.UserID); ok {
+		updated = published
+	}
+
+	writeJSON(w, http.StatusOK, newOwnedTreeResponse(updated))
+}
+
+// DeleteTree deletes a tree and its links. The default tree cannot be
+// deleted; make another tree the default first.
+func (h *Handler) DeleteTree(w http.ResponseWriter, r *http.Request) {
+	tree, ok := h.loadOwnedTree(w, r)
+	if !ok {
+		return
+	}
+	if tree.IsDefault {
+		writeError(w, http.StatusConflict, "cannot delete the default tree")
+		return
+	}
+
+	if err := repo.DeleteTree(h.DB, tree.ID, tree.UserID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete tree")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// loadOwnedTree loads the caller's tree named by the {id} URL parameter.
+func (h *Handler) loadOwnedTree(w http.ResponseWriter, r *http.Request) (models.Tree, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return models.Tree{}, false
+	}
+
+	treeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || treeID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid tree id")
+		return models.Tree{}, false
+	}
+
+	tree, err := repo.GetTreeByIDAndUser(h.DB, treeID, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "tree not found")
+			return models.Tree{}, false
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load tree")
+		return models.Tree{}, false
+	}
+	return tree, true
+}
+
+// createDefaultTree creates the tree a new account starts with, its slug made
+// from name (the username, or a display name) where possible.
+func (h *Handler) createDefaultTree(userID int64, title, name string) (models.Tree, error) {
+	return h.createTreeWithGeneratedSlug(userID, title, name)
+}
+
+// createTreeWithGeneratedSlug tries the slug made from name as is, then with
+// random suffixes until one is free.
+func (h *Handler) createTreeWithGeneratedSlug(userID int64, title, name string) (models.Tree, error) {
+	base := services.SlugifyTreeName(name)
+	slug := base
+	for attempt := 0; attempt < treeSlugAttempts; attempt++ {
+		if attempt > 0 || services.ValidateTreeSlug(slug) != nil {
+			var err error
+			slug, err = services.GenerateTreeSlug(base)
+			if err != nil {
+				return models.Tree{}, err
+			}
+		}
+		tree, err := repo.CreateTree(h.DB, userID, title, slug)
+		if errors.Is(err, repo.ErrDuplicate) {
+			continue
+		}
+		return tree, err
+	}
+	return models.Tree{}, errors.New("no free tree slug")
+}
+
+func validateTreeTitle(title string) string {
+	if title == "" {
+		return "title is required"
+	}
+	if len(title) > treeTitleMaxLength {
+		return "title must be at most " + strconv.Itoa(treeTitleMaxLength) + " characters"
+	}
+	return ""
+}
+
+func newOwnedTreeResponse(tree models.Tree) ownedTreeResponse {
+	resp := ownedTreeResponse{
+		ID:          tree.ID,
+		Title:       tree.Title,
+		Slug:        tree.Slug,
+		IsDefault:   tree.IsDefault,
+		treeProfile: newTreeProfile(tree),
+		Theme:       newOwnedThemeResponse(tree),
+		AutoPublish: tree.AutoPublish,
+		CreatedAt:   tree.CreatedAt,
+		UpdatedAt:   tree.UpdatedAt,
+	}
+	if tree.PublishedAt.Valid {
+		resp.PublishedAt = &tree.PublishedAt.Time
+	}
+	return resp
+}
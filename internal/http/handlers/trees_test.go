@@ -0,0 +1,65 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Concurrent creates for a user without a default tree must leave exactly
+// one default.
+func TestCreateTreeConcurrentDefault(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup(t)
+	if _, err := s.DB.Exec(`UPDATE users SET email_verified_at = NOW() WHERE id = $1`, alice.User.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DB.Exec(`DELETE FROM trees WHERE user_id = $1`, alice.User.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// s.do may only fail the test from the test goroutine.
+	const creates = 4
+	statuses := make([]int, creates)
+	errs := make([]error, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodPost, s.URL+"/trees", strings.NewReader(`{"title": "Tree `+strconv.Itoa(i)+`"}`))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+alice.Token)
+			resp, err := s.Client().Do(req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	for i := range statuses {
+		if errs[i] != nil {
+			t.Fatalf("create %d: %v", i, errs[i])
+		}
+		if statuses[i] != http.StatusCreated {
+			t.Fatalf("create %d: status %d, want %d", i, statuses[i], http.StatusCreated)
+		}
+	}
+
+	var defaults int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM trees WHERE user_id = $1 AND is_default`, alice.User.ID).Scan(&defaults); err != nil {
+		t.Fatal(err)
+	}
+	if defaults != 1 {
+		t.Fatalf("%d default trees, want 1", defaults)
+	}
+}
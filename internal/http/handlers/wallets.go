@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/http/middleware"
+	"chainhub-api/internal/models"
+	"chainhub-api/internal/repo"
+	"chainhub-api/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type walletChallengeRequest struct {
+	Address string `json:"address"`
+}
+
+type walletChallengeResponse struct {
+	Nonce     string    `json:"nonce"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type linkWalletRequest struct {
+	Nonce     string `json:"nonce"`
+	Signature string `json:"signature"`
+}
+
+func (h *Handler) CreateWalletChallenge(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req walletChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	address, err := services.ChecksumAddress(strings.TrimSpace(req.Address))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid wallet address")
+		return
+	}
+
+	owner, err := repo.GetUserByWalletAddress(h.DB, address)
+	if err == nil {
+		if owner.ID == userID {
+			writeError(w, http.StatusConflict, "wallet already linked to this account")
+		} else {
+			writeError(w, http.StatusConflict, "wallet already linked to another account")
+		}
+		return
+	}
+	if !errors.Is(err, repo.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "failed to load wallet")
+		return
+	}
+
+	nonce, err := services.GenerateNonce()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create nonce")
+		return
+	}
+
+	now := time.Now()
+	challenge := models.WalletChallenge{
+		Nonce:         nonce,
+		UserID:        userID,
+		WalletAddress: address,
+		Message:       services.WalletLinkMessage(h.Config.SIWEDomain, address, userID, nonce, now),
+		ExpiresAt:     now.Add(h.Config.SIWENonceTTL),
+	}
+	if err := repo.CreateWalletChallenge(h.DB, challenge); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store challenge")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, walletChallengeResponse{
+		Nonce:     challenge.Nonce,
+		Message:   challenge.Message,
+		ExpiresAt: challenge.ExpiresAt.UTC(),
+	})
+}
+
+func (h *Handler) LinkWallet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req linkWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	if req.Nonce == "" || req.Signature == "" {
+		writeError(w, http.StatusBadRequest, "nonce and signature are required")
+		return
+	}
+
+	// Consume the challenge up front so a bad signature cannot be retried.
+	challenge, err := repo.ConsumeWalletChallenge(h.DB, userID, req.Nonce)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusUnauthorized, "invalid or expired challenge")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load challenge")
+		return
+	}
+
+	signer, err := services.RecoverPersonalSignAddress(challenge.Message, req.Signature)
+	if err != nil || signer != challenge.WalletAddress {
+		writeError(w, http.StatusUnauthorized, "signature does not match address")
+		return
+	}
+
+	user, err := repo.LinkWalletToUser(h.DB, userID, challenge.WalletAddress)
+	if err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			writeError(w, http.StatusConflict, "wallet already linked to another account")
+			return
+		}
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusConflict, "account already has a linked wallet")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to link wallet")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newUserResponse(user))
+}
+
+func (h *Handler) UnlinkWallet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	address, err := services.ChecksumAddress(chi.URLParam(r, "address"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid wallet address")
+		return
+	}
+
+	user, err := repo.GetUserByID(h.DB, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	if user.WalletAddress.String != address {
+		writeError(w, http.StatusNotFound, "wallet not found")
+		return
+	}
+	if err := repo.UnlinkWalletFromUser(h.DB, userID, address); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, http.StatusConflict, "cannot unlink the only login method")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to unlink wallet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"deleted": true})
+}
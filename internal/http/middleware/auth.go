@@ -10,9 +10,32 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+	scopesKey    contextKey = "scopes"
+	roleKey      contextKey = "role"
+)
+
+// SessionChecker reports whether a session has been revoked server-side.
+type SessionChecker interface {
+	IsSessionActive(sessionID int64) (bool, error)
+}
+
+// APITokenChecker resolves personal access tokens. ok is false when token is
+// not one.
+type APITokenChecker interface {
+	AuthenticateAPIToken(token string) (userID int64, scopes []string, ok bool, err error)
+}
+
+// AccessTokenParser verifies an access JWT and returns its claims. It must
+// reject tokens of any other type or audience.
+type AccessTokenParser func(token string) (jwt.MapClaims, error)
 
-func Auth(secret string) func(http.Handler) http.Handler {
+// Auth accepts either an access JWT from a login session or a personal access
+// token. Sessions are granted every scope and carry the user's role; API
+// tokens only their own scopes.
+func Auth(parseAccessToken AccessTokenParser, sessions SessionChecker, apiTokens APITokenChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			tokenStr := bearerToken(r.Header.Get("Authorization"))
@@ -21,19 +44,27 @@ func Auth(secret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return []byte(secret), nil
-			})
-			if err != nil || !token.Valid {
+			userID, scopes, ok, err := apiTokens.AuthenticateAPIToken(tokenStr)
+			if err != nil {
+				http.Error(w, "failed to check token", http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				ctx := context.WithValue(r.Context(), userIDKey, userID)
+				ctx = context.WithValue(ctx, scopesKey, scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			claims, err := parseAccessToken(tokenStr)
+			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
+			// Single-purpose tokens (email verification and the like) are
+			// signed with the same keys but must never grant API access.
+			if _, ok := claims["pur"]; ok {
 				http.Error(w, "invalid token claims", http.StatusUnauthorized)
 				return
 			}
@@ -44,13 +75,42 @@ func Auth(secret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			userID, ok := parseUserID(sub)
+			userID, ok = parseIDClaim(sub)
 			if !ok {
 				http.Error(w, "invalid token claims", http.StatusUnauthorized)
 				return
 			}
 
+			sessionID, ok := parseIDClaim(claims["sid"])
+			if !ok {
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
+				return
+			}
+
+			// Tokens issued before roles existed carry none.
+			role, _ := claims["role"].(string)
+			if role == "" {
+				role = RoleUser
+			}
+			if !IsKnownRole(role) {
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
+				return
+			}
+
+			active, err := sessions.IsSessionActive(sessionID)
+			if err != nil {
+				http.Error(w, "failed to check session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "session revoked", http.StatusUnauthorized)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			ctx = context.WithValue(ctx, sessionIDKey, sessionID)
+			ctx = context.WithValue(ctx, scopesKey, AllScopes)
+			ctx = context.WithValue(ctx, roleKey, role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -61,6 +121,11 @@ func GetUserID(ctx context.Context) (int64, bool) {
 	return userID, ok
 }
 
+func GetSessionID(ctx context.Context) (int64, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey).(int64)
+	return sessionID, ok
+}
+
 func bearerToken(header string) string {
 	if header == "" {
 		return ""
@@ -72,7 +137,7 @@ func bearerToken(header string) string {
 	return strings.TrimSpace(parts[1])
 }
 
-func parseUserID(value interface{}) (int64, bool) {
+func parseIDClaim(value interface{}) (int64, bool) {
 	switch v := value.(type) {
 	case float64:
 		return int64(v), true
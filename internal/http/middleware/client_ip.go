@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const clientIPKey contextKey = "clientIP"
+
+// ClientIP resolves the address of the client behind any trusted proxies and
+// stores it for GetClientIP. X-Forwarded-For is only read when the request
+// arrives from a trusted proxy, and then from the right: each trusted hop may
+// have appended the address it received from, but anything left of the first
+// untrusted address was written by the client and is ignored.
+func ClientIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), trustedProxies)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+// GetClientIP returns the address ClientIP resolved, or "" outside it.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+func resolveClientIP(remoteAddr string, forwardedFor []string, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	peer = peer.Unmap()
+	if !isTrusted(peer, trustedProxies) {
+		return peer.String()
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A garbled hop was not written by a proxy we trust; the last
+			// address we could vouch for is the best we have.
+			break
+		}
+		peer = addr.Unmap()
+		if !isTrusted(peer, trustedProxies) {
+			break
+		}
+	}
+	return peer.String()
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
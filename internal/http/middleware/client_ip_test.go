@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+
+	for _, tc := range []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{"direct", "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"untrusted peer cannot forward", "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.1:4000", nil, "10.0.0.1"},
+		{"ipv6 trusted proxy", "[::1]:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed hops are ignored", "10.0.0.1:4000", []string{"1.1.1.1, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"repeated headers", "10.0.0.1:4000", []string{"1.1.1.1", "198.51.100.1", "10.0.0.2"}, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.1:4000", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"garbled hop", "10.0.0.1:4000", []string{"1.1.1.1, not-an-ip, 10.0.0.2"}, "10.0.0.2"},
+		{"mapped ipv4", "[::ffff:203.0.113.7]:4000", nil, "203.0.113.7"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetClientIP(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tc.want {
+				t.Fatalf("client IP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
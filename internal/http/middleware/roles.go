@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// Roles, each allowed everything the ones before it are.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+func IsKnownRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants everything min does.
+func RoleAtLeast(role, min string) bool {
+	return roleRanks[role] >= roleRanks[min]
+}
+
+// GetRole returns the role carried by the access token. API tokens carry
+// none, so they never pass RequireRole.
+func GetRole(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey).(string)
+	return role
+}
+
+// RequireRole rejects requests from users below min.
+func RequireRole(min string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := GetRole(r.Context())
+			if role == "" || !RoleAtLeast(role, min) {
+				http.Error(w, "forbidden: "+min+" role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// Scopes a personal access token can be granted. Login sessions hold all of them.
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeTreeRead   = "tree:read"
+	ScopeTreeWrite  = "tree:write"
+)
+
+var AllScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeTreeRead, ScopeTreeWrite}
+
+func IsKnownScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func GetScopes(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey).([]string)
+	return scopes
+}
+
+func HasScope(ctx context.Context, scope string) bool {
+	for _, s := range GetScopes(ctx) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope rejects requests whose credentials were not granted scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				http.Error(w, "insufficient scope: "+scope+" required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects API tokens. Account settings, including creating
+// more tokens, are only reachable from a login session.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetSessionID(r.Context()); !ok {
+			http.Error(w, "login session required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
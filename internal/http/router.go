@@ -15,19 +15,100 @@ func NewRouter(handler *handlers.Handler) http.Handler {
 	r.Use(chimw.RequestID)
 	r.Use(chimw.Logger)
 	r.Use(chimw.Recoverer)
+	r.Use(authmw.ClientIP(handler.Config.TrustedProxies))
 	r.Use(authmw.CORS(handler.Config.FrontendURL))
 
+	auth := authmw.Auth(handler.Keys.ParseAccessToken, handler.Sessions, handler.APITokens)
+
 	r.Post("/signup", handler.Signup)
 	r.Post("/login", handler.Login)
+	r.Post("/auth/refresh", handler.Refresh)
+	r.Post("/auth/logout", handler.Logout)
+	r.Post("/auth/password/forgot", handler.ForgotPassword)
+	r.Post("/auth/password/reset", handler.ResetPassword)
+	r.Post("/auth/email/verify", handler.VerifyEmail)
+	r.Post("/auth/mfa/verify", handler.VerifyMFA)
+	r.Post("/auth/magic-link", handler.RequestMagicLink)
+	r.Get("/auth/magic-link/callback", handler.MagicLinkCallback)
+	r.With(auth, authmw.RequireSession).Post("/auth/email/resend", handler.ResendVerificationEmail)
+	r.Post("/auth/passkey/login/begin", handler.BeginPasskeyLogin)
+	r.Post("/auth/passkey/login/finish", handler.FinishPasskeyLogin)
+	r.Get("/auth/oidc/providers", handler.ListOIDCProviders)
+	r.Post("/auth/oidc/{provider}/start", handler.StartOIDCLogin)
+	r.Post("/auth/oidc/callback", handler.OIDCCallback)
+	r.Get("/auth/siwe/nonce", handler.SIWENonce)
+	r.Post("/auth/siwe/verify", handler.SIWEVerify)
 	r.Get("/healthz", handler.Health)
+	r.Get("/.well-known/jwks.json", handler.JWKS)
 	r.Get("/tree/{username}", handler.GetTreeByUsername)
+	r.Get("/t/{slug}", handler.GetTreeBySlug)
+	r.Get("/themes", handler.ListThemes)
+
+	r.Route("/me", func(r chi.Router) {
+		r.Use(auth, authmw.RequireSession)
+		r.Get("/", handler.GetMe)
+		r.Patch("/", handler.UpdateMe)
+		r.Delete("/", handler.DeleteMe)
+		r.Get("/export", handler.ExportMe)
+		r.Post("/wallets/challenge", handler.CreateWalletChallenge)
+		r.Post("/wallets", handler.LinkWallet)
+		r.Delete("/wallets/{address}", handler.UnlinkWallet)
+		r.Get("/identities", handler.ListIdentities)
+		r.Post("/identities/{provider}", handler.StartOIDCLink)
+		r.Delete("/identities/{id}", handler.DeleteIdentity)
+		r.Get("/tokens", handler.ListAPITokens)
+		r.Post("/tokens", handler.CreateAPIToken)
+		r.Delete("/tokens/{id}", handler.DeleteAPIToken)
+		r.Get("/sessions", handler.ListSessions)
+		r.Delete("/sessions/{id}", handler.RevokeSession)
+		r.Post("/mfa/totp", handler.EnrollTOTP)
+		r.Post("/mfa/totp/confirm", handler.ConfirmTOTP)
+		r.Delete("/mfa/totp", handler.DisableTOTP)
+		r.Post("/passkeys/register/begin", handler.BeginPasskeyRegistration)
+		r.Post("/passkeys/register/finish", handler.FinishPasskeyRegistration)
+		r.Get("/passkeys", handler.ListPasskeys)
+		r.Delete("/passkeys/{id}", handler.DeletePasskey)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(auth, authmw.RequireSession, authmw.RequireRole(authmw.RoleModerator))
+		r.Get("/users", handler.AdminListUsers)
+		r.Post("/users/{id}/suspend", handler.AdminSuspendUser)
+		r.Post("/users/{id}/unsuspend", handler.AdminUnsuspendUser)
+		r.With(authmw.RequireRole(authmw.RoleAdmin)).Put("/users/{id}/role", handler.AdminSetUserRole)
+	})
+
+	r.Route("/trees", func(r chi.Router) {
+		r.Use(auth)
+		r.Group(func(r chi.Router) {
+			r.Use(authmw.RequireScope(authmw.ScopeTreeRead))
+			r.Get("/", handler.ListTrees)
+			r.Get("/{id}", handler.GetTree)
+			r.Get("/{id}/preview", handler.PreviewTree)
+			r.Get("/{id}/versions", handler.ListTreeVersions)
+			r.Get("/{id}/versions/diff", handler.DiffTreeVersions)
+			r.Get("/{id}/versions/{version}", handler.GetTreeVersion)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(authmw.RequireScope(authmw.ScopeTreeWrite), handler.RequireVerifiedEmail)
+			r.Post("/", handler.CreateTree)
+			r.Patch("/{id}", handler.UpdateTree)
+			r.Delete("/{id}", handler.DeleteTree)
+			r.Post("/{id}/publish", handler.PublishTree)
+			r.Post("/{id}/discard", handler.DiscardTreeDraft)
+			r.Post("/{id}/versions/{version}/restore", handler.RestoreTreeVersion)
+		})
+	})
 
 	r.Route("/links", func(r chi.Router) {
-		r.Use(authmw.Auth(handler.Config.JWTSecret))
-		r.Get("/", handler.ListLinks)
-		r.Post("/", handler.CreateLink)
-		r.Put("/{id}", handler.UpdateLink)
-		r.Delete("/{id}", handler.DeleteLink)
+		r.Use(auth)
+		r.With(authmw.RequireScope(authmw.ScopeLinksRead)).Get("/", handler.ListLinks)
+		r.Group(func(r chi.Router) {
+			r.Use(authmw.RequireScope(authmw.ScopeLinksWrite), handler.RequireVerifiedEmail)
+			r.Post("/", handler.CreateLink)
+			r.Put("/{id}", handler.UpdateLink)
+			r.Delete("/{id}", handler.DeleteLink)
+		})
 	})
 
 	return r
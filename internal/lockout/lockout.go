@@ -0,0 +1,118 @@
+package lockout
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"chainhub-api/internal/config"
+)
+
+// State is what a Store keeps per throttled key, such as an IP address or an
+// account.
+type State struct {
+	Failures     int
+	BlockedUntil time.Time
+}
+
+// Store holds failure counts. Implementations must make RecordFailure atomic
+// so that concurrent attempts on several replicas are all counted.
+type Store interface {
+	Get(key string) (State, error)
+	// RecordFailure adds a failure and returns the new count. A key whose last
+	// failure is older than resetBefore starts over from one.
+	RecordFailure(key string, resetBefore time.Time) (int, error)
+	// Block extends the key's block to until; it never shortens it.
+	Block(key string, until time.Time) error
+	Reset(key string) error
+}
+
+// New returns the store selected by cfg.LockoutStore.
+func New(cfg config.Config, db *sql.DB) (Store, error) {
+	switch cfg.LockoutStore {
+	case "postgres":
+		return &PostgresStore{DB: db}, nil
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown lockout store %q", cfg.LockoutStore)
+	}
+}
+
+// Policy decides how long to wait after a number of failures. The first
+// FreeAttempts failures cost nothing, each further one doubles the delay
+// starting at BackoffBase (capped at BackoffMax), and MaxFailures locks the
+// key for LockoutDuration. Counts are forgotten after ResetAfter without a
+// failure.
+type Policy struct {
+	FreeAttempts    int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	MaxFailures     int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+func (p Policy) delay(failures int) time.Duration {
+	if failures >= p.MaxFailures {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	d := p.BackoffBase
+	for i := p.FreeAttempts + 1; i < failures && d < p.BackoffMax; i++ {
+		d *= 2
+	}
+	if d > p.BackoffMax {
+		d = p.BackoffMax
+	}
+	return d
+}
+
+// Limiter applies a Policy to keys in a Store. Several limiters can share a
+// store as long as their keys do not collide.
+type Limiter struct {
+	store  Store
+	policy Policy
+	prefix string
+}
+
+func NewLimiter(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, prefix: prefix + ":"}
+}
+
+// Wait returns how long key must wait before its next attempt, or zero.
+func (l *Limiter) Wait(key string) (time.Duration, error) {
+	state, err := l.store.Get(l.prefix + key)
+	if err != nil {
+		return 0, err
+	}
+	if wait := time.Until(state.BlockedUntil); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failed attempt and returns the wait it now imposes.
+func (l *Limiter) Fail(key string) (time.Duration, error) {
+	now := time.Now()
+	failures, err := l.store.RecordFailure(l.prefix+key, now.Add(-l.policy.ResetAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	wait := l.policy.delay(failures)
+	if wait > 0 {
+		if err := l.store.Block(l.prefix+key, now.Add(wait)); err != nil {
+			return 0, err
+		}
+	}
+	return wait, nil
+}
+
+// Reset forgets key's failures, e.g. after a successful login.
+func (l *Limiter) Reset(key string) error {
+	return l.store.Reset(l.prefix + key)
+}
@@ -0,0 +1,76 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+const memoryStoreMaxEntries = 100000
+
+// MemoryStore keeps lockout state in process. It suits a single replica and
+// development; several replicas need PostgresStore to share counts.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	state       State
+	lastFailure time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Get(key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries[key].state, nil
+}
+
+func (s *MemoryStore) RecordFailure(key string, resetBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= memoryStoreMaxEntries {
+		s.evictLocked(resetBefore)
+	}
+
+	entry := s.entries[key]
+	if entry.lastFailure.Before(resetBefore) {
+		entry.state.Failures = 0
+	}
+	entry.state.Failures++
+	entry.lastFailure = time.Now()
+	s.entries[key] = entry
+	return entry.state.Failures, nil
+}
+
+func (s *MemoryStore) Block(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	if until.After(entry.state.BlockedUntil) {
+		entry.state.BlockedUntil = until
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) evictLocked(resetBefore time.Time) {
+	now := time.Now()
+	for key, entry := range s.entries {
+		if entry.lastFailure.Before(resetBefore) && entry.state.BlockedUntil.Before(now) {
+			delete(s.entries, key)
+		}
+	}
+}
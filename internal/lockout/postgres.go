@@ -0,0 +1,33 @@
+package lockout
+
+import (
+	"database/sql"
+	"time"
+
+	"chainhub-api/internal/repo"
+)
+
+// PostgresStore shares lockout state between API replicas.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+func (s *PostgresStore) Get(key string) (State, error) {
+	failures, blockedUntil, err := repo.GetLoginThrottle(s.DB, key)
+	if err != nil {
+		return State{}, err
+	}
+	return State{Failures: failures, BlockedUntil: blockedUntil.Time}, nil
+}
+
+func (s *PostgresStore) RecordFailure(key string, resetBefore time.Time) (int, error) {
+	return repo.RecordLoginThrottleFailure(s.DB, key, resetBefore)
+}
+
+func (s *PostgresStore) Block(key string, until time.Time) error {
+	return repo.BlockLoginThrottle(s.DB, key, until)
+}
+
+func (s *PostgresStore) Reset(key string) error {
+	return repo.DeleteLoginThrottle(s.DB, key)
+}
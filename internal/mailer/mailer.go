@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"chainhub-api/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as password reset links.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by cfg.Mailer.
+func New(cfg config.Config) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}, nil
+	case "outbox":
+		return &OutboxMailer{Dir: cfg.MailOutboxDir, From: cfg.MailFrom}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+func render(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// headerValue strips line breaks so user-supplied values cannot inject headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
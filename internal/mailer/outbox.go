@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxMailer writes each message to Dir as an .eml file instead of sending
+// it. It is meant for local development and tests.
+type OutboxMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *OutboxMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(m.Dir, name), render(m.From, msg), 0o644)
+}
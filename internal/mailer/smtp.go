@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, render(m.From, msg)); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// APIToken is a personal access token. Only the hash of the token is kept;
+// TokenPrefix is the start of the token so users can tell tokens apart.
+type APIToken struct {
+	ID          int64
+	UserID      int64
+	Name        string
+	TokenPrefix string
+	TokenHash   string
+	Scopes      []string
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	CreatedAt   time.Time
+}
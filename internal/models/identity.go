@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Identity links an account at an OpenID Connect provider to a user.
+type Identity struct {
+	ID          int64
+	UserID      int64
+	Provider    string
+	Subject     string
+	Email       sql.NullString
+	LastLoginAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+// OIDCState is a pending authorization request. UserID is set when a signed-in
+// user is linking a new identity rather than logging in.
+type OIDCState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	UserID       sql.NullInt64
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
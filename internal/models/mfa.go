@@ -0,0 +1,14 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type TOTPCredential struct {
+	UserID       int64
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep sql.NullInt64
+	CreatedAt    time.Time
+}
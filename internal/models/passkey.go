@@ -0,0 +1,31 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Passkey struct {
+	ID              int64
+	UserID          int64
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	Transports      string
+	AAGUID          []byte
+	SignCount       int64
+	BackupEligible  bool
+	BackupState     bool
+	Name            string
+	LastUsedAt      sql.NullTime
+	CreatedAt       time.Time
+}
+
+type WebAuthnChallenge struct {
+	ID          string
+	UserID      sql.NullInt64
+	Ceremony    string
+	SessionData []byte
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
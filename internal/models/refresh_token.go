@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	SessionID int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
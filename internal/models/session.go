@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Session struct {
+	ID         int64
+	UserID     int64
+	Device     string
+	IPAddress  string
+	UserAgent  string
+	LastSeenAt time.Time
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
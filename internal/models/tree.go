@@ -1,10 +1,81 @@
 package models
 
-import "time"
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
 
 type Tree struct {
+	ID          int64
+	UserID      int64
+	Title       string
+	Slug        string
+	IsDefault   bool
+	DisplayName string
+	Bio         string
+	AvatarURL   string
+	Socials     []TreeSocial
+	// ThemePreset names a preset from the theme catalog and ThemeOverrides
+	// holds the JSON changes made to it.
+	ThemePreset    string
+	ThemeOverrides []byte
+	// PublishedAt is when the public version was last replaced. The tree's
+	// own fields and links are the draft; the public version is a
+	// TreeSnapshot stored alongside. With AutoPublish every edit is published
+	// as soon as it is saved.
+	PublishedAt sql.NullTime
+	AutoPublish bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TreeSocial is one social icon on a tree. It is stored as JSON, in order.
+type TreeSocial struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+// TreeSnapshot is the content of a tree frozen at one moment: everything a
+// public page shows except the slug and owner, which always take their
+// current values.
+type TreeSnapshot struct {
+	Title          string          `json:"title"`
+	DisplayName    string          `json:"display_name"`
+	Bio            string          `json:"bio"`
+	AvatarURL      string          `json:"avatar_url"`
+	Socials        []TreeSocial    `json:"socials"`
+	ThemePreset    string          `json:"theme_preset"`
+	ThemeOverrides json.RawMessage `json:"theme_overrides"`
+	Links          []SnapshotLink  `json:"links"`
+}
+
+// SnapshotLink keeps the link's ID so that restoring a snapshot brings back
+// the same links rather than copies.
+type SnapshotLink struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Position int    `json:"position"`
+	IsActive bool   `json:"is_active"`
+}
+
+// Actions recorded with a tree version.
+const (
+	TreeVersionCreate      = "create"
+	TreeVersionPublish     = "publish"
+	TreeVersionAutoPublish = "auto_publish"
+	TreeVersionRestore     = "restore"
+)
+
+// TreeVersion is one published state of a tree, kept unchanged for history
+// and rollback. Versions are numbered from 1 per tree.
+type TreeVersion struct {
 	ID        int64
-	UserID    int64
-	Title     string
+	TreeID    int64
+	Version   int
+	Snapshot  TreeSnapshot
+	Action    string
+	AuthorID  sql.NullInt64
 	CreatedAt time.Time
 }
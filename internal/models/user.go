@@ -11,5 +11,8 @@ type User struct {
 	Username     sql.NullString
 	PasswordHash sql.NullString
 	WalletAddress sql.NullString
+	EmailVerifiedAt sql.NullTime
+	Role         string
+	SuspendedAt  sql.NullTime
 	CreatedAt    time.Time
 }
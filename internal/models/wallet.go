@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type WalletChallenge struct {
+	Nonce         string
+	UserID        int64
+	WalletAddress string
+	Message       string
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
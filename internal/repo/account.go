@@ -0,0 +1,336 @@
+package repo
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// AccountUpdate is a set of account changes UpdateAccount makes together.
+// Empty fields are left as they are.
+type AccountUpdate struct {
+	Username         string
+	UsernameSkeleton string
+	RedirectUntil    time.Time
+
+	// PendingEmail is an address the user wants to switch to. It only
+	// replaces the current email once verified, see MarkEmailVerified.
+	PendingEmail string
+
+	// PasswordHash replaces the password. Outstanding reset links are
+	// invalidated and every session but KeepSessionID is revoked.
+	PasswordHash  string
+	KeepSessionID int64
+}
+
+// UpdateAccount applies update in one transaction, so a change that fails
+// leaves the others unmade. It returns ErrDuplicate if the username is taken,
+// and the IDs of sessions revoked by a password change.
+func UpdateAccount(db *sql.DB, userID int64, update AccountUpdate) ([]int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if update.Username != "" {
+		if err := changeUsernameTx(tx, userID, update.Username, update.UsernameSkeleton, update.RedirectUntil); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.PendingEmail != "" {
+		if _, err := tx.Exec(
+			`UPDATE users SET pending_email = $1 WHERE id = $2`,
+			update.PendingEmail,
+			userID,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	var revoked []int64
+	if update.PasswordHash != "" {
+		if revoked, err = changePasswordTx(tx, userID, update.PasswordHash, update.KeepSessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// changeUsernameTx renames a user and keeps the old name reserved for them,
+// redirecting to the account, until redirectUntil. Names that look like one
+// still held by another account's redirect cannot be taken; a user may take
+// back their own.
+func changeUsernameTx(tx *sql.Tx, userID int64, newUsername, skeleton string, redirectUntil time.Time) error {
+	var held bool
+	if err := tx.QueryRow(
+		`SELECT EXISTS(
+			SELECT 1 FROM username_redirects
+			WHERE (skeleton = $1 OR LOWER(old_username) = LOWER($2))
+			  AND user_id <> $3 AND expires_at > NOW()
+		)`,
+		skeleton,
+		newUsername,
+		userID,
+	).Scan(&held); err != nil {
+		return err
+	}
+	if held {
+		return ErrDuplicate
+	}
+
+	if _, err := tx.Exec(
+		`DELETE FROM username_redirects
+		 WHERE skeleton = $1 OR LOWER(old_username) = LOWER($2)`,
+		skeleton,
+		newUsername,
+	); err != nil {
+		return err
+	}
+
+	var oldUsername, oldSkeleton sql.NullString
+	if err := tx.QueryRow(
+		`SELECT username, username_skeleton FROM users WHERE id = $1 FOR UPDATE`,
+		userID,
+	).Scan(&oldUsername, &oldSkeleton); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE users SET username = $1, username_skeleton = $2 WHERE id = $3`,
+		newUsername,
+		skeleton,
+		userID,
+	); err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	// Lookups ignore case, so a change of case alone needs no redirect.
+	if oldUsername.Valid && !strings.EqualFold(oldUsername.String, newUsername) {
+		if _, err := tx.Exec(
+			`INSERT INTO username_redirects (old_username, skeleton, user_id, expires_at)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (old_username) DO UPDATE
+			 SET skeleton = EXCLUDED.skeleton, user_id = EXCLUDED.user_id,
+				expires_at = EXCLUDED.expires_at, created_at = NOW()`,
+			oldUsername.String,
+			oldSkeleton,
+			userID,
+			redirectUntil,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetUsernameRedirect returns the current username of the account that used
+// to be called oldUsername, while the redirect lasts.
+func GetUsernameRedirect(db *sql.DB, oldUsername string) (string, error) {
+	var username sql.NullString
+	err := db.QueryRow(
+		`SELECT u.username
+		 FROM username_redirects r
+		 JOIN users u ON u.id = r.user_id
+		 WHERE LOWER(r.old_username) = LOWER($1) AND r.expires_at > NOW()`,
+		oldUsername,
+	).Scan(&username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	if !username.Valid {
+		return "", ErrNotFound
+	}
+	return username.String, nil
+}
+
+func GetPendingEmail(db *sql.DB, userID int64) (sql.NullString, error) {
+	var email sql.NullString
+	err := db.QueryRow(
+		`SELECT pending_email FROM users WHERE id = $1`,
+		userID,
+	).Scan(&email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullString{}, ErrNotFound
+		}
+		return sql.NullString{}, err
+	}
+	return email, nil
+}
+
+// changePasswordTx sets a new password hash, invalidates outstanding reset
+// links, and revokes every session except keepSessionID. It returns the
+// revoked session IDs.
+func changePasswordTx(tx *sql.Tx, userID int64, passwordHash string, keepSessionID int64) ([]int64, error) {
+	if _, err := tx.Exec(
+		`UPDATE users SET password_hash = $1 WHERE id = $2`,
+		passwordHash,
+		userID,
+	); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE password_reset_tokens
+		 SET used_at = NOW()
+		 WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		`UPDATE sessions
+		 SET revoked_at = NOW()
+		 WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		 RETURNING id`,
+		userID,
+		keepSessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE refresh_tokens
+		 SET revoked_at = NOW()
+		 WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL`,
+		userID,
+		keepSessionID,
+	); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// ReplacePasswordHash swaps in a rehash of the same password. It fails with
+// ErrNotFound, and changes nothing, if the password changed meanwhile.
+func ReplacePasswordHash(db *sql.DB, userID int64, oldHash, newHash string) error {
+	result, err := db.Exec(
+		`UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`,
+		newHash,
+		userID,
+		oldHash,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UsernameSkeletonBackfill is a row whose skeleton has not been computed yet.
+type UsernameSkeletonBackfill struct {
+	UserID   int64
+	Username string
+}
+
+func ListUsersWithoutUsernameSkeleton(db *sql.DB) ([]UsernameSkeletonBackfill, error) {
+	rows, err := db.Query(
+		`SELECT id, username FROM users
+		 WHERE username IS NOT NULL AND username_skeleton IS NULL
+		 ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []UsernameSkeletonBackfill
+	for rows.Next() {
+		var p UsernameSkeletonBackfill
+		if err := rows.Scan(&p.UserID, &p.Username); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// SetUsernameSkeleton fails with ErrDuplicate if an older account already has
+// a look-alike name.
+func SetUsernameSkeleton(db *sql.DB, userID int64, skeleton string) error {
+	_, err := db.Exec(
+		`UPDATE users SET username_skeleton = $1 WHERE id = $2`,
+		skeleton,
+		userID,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+func ListRedirectsWithoutSkeleton(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(
+		`SELECT old_username FROM username_redirects WHERE skeleton IS NULL`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func SetRedirectSkeleton(db *sql.DB, oldUsername, skeleton string) error {
+	_, err := db.Exec(
+		`UPDATE username_redirects SET skeleton = $1 WHERE old_username = $2`,
+		skeleton,
+		oldUsername,
+	)
+	return err
+}
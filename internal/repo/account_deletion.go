@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ScheduleAccountDeletion marks the user for deletion at deleteAt, signs them
+// out everywhere and deletes their personal access tokens, so that only a
+// fresh login can use the account or cancel the deletion. It returns the
+// revoked session IDs.
+func ScheduleAccountDeletion(db *sql.DB, userID int64, deleteAt time.Time) ([]int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE users SET deletion_scheduled_at = $1 WHERE id = $2`,
+		deleteAt,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	ids, err := revokeAllSessionsTx(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM api_tokens WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CancelAccountDeletion clears a scheduled deletion and reports whether there
+// was one.
+func CancelAccountDeletion(db *sql.DB, userID int64) (bool, error) {
+	result, err := db.Exec(
+		`UPDATE users
+		 SET deletion_scheduled_at = NULL
+		 WHERE id = $1 AND deletion_scheduled_at IS NOT NULL`,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func GetAccountDeletionSchedule(db *sql.DB, userID int64) (sql.NullTime, error) {
+	var at sql.NullTime
+	err := db.QueryRow(
+		`SELECT deletion_scheduled_at FROM users WHERE id = $1`,
+		userID,
+	).Scan(&at)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullTime{}, ErrNotFound
+		}
+		return sql.NullTime{}, err
+	}
+	return at, nil
+}
+
+// DeleteDueAccounts hard-deletes accounts whose deletion date has passed.
+// Everything the user owns goes with them through ON DELETE CASCADE.
+func DeleteDueAccounts(db *sql.DB) (int64, error) {
+	result, err := db.Exec(
+		`DELETE FROM users WHERE deletion_scheduled_at <= NOW()`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
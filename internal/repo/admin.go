@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"chainhub-api/internal/models"
+)
+
+// AdminUser is a user as shown to moderators and admins.
+type AdminUser struct {
+	models.User
+	SuspensionReason sql.NullString
+}
+
+// UserFilter narrows ListUsers. Zero values match everything.
+type UserFilter struct {
+	// Query matches the start of the email or username, case-insensitively.
+	Query     string
+	Role      string
+	Suspended sql.NullBool
+	Limit     int
+	Offset    int
+}
+
+const adminUserColumns = `id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at, suspension_reason`
+
+func scanAdminUser(row interface{ Scan(...interface{}) error }) (AdminUser, error) {
+	var u AdminUser
+	err := row.Scan(&u.ID, &u.Email, &u.Username, &u.PasswordHash, &u.WalletAddress, &u.EmailVerifiedAt, &u.Role, &u.SuspendedAt, &u.CreatedAt, &u.SuspensionReason)
+	return u, err
+}
+
+func ListUsers(db *sql.DB, filter UserFilter) ([]AdminUser, error) {
+	var where []string
+	var args []interface{}
+	if filter.Query != "" {
+		args = append(args, escapeLike(strings.ToLower(filter.Query))+"%")
+		where = append(where, fmt.Sprintf("(LOWER(email) LIKE $%d OR LOWER(username) LIKE $%d)", len(args), len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		where = append(where, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if filter.Suspended.Valid {
+		if filter.Suspended.Bool {
+			where = append(where, "suspended_at IS NOT NULL")
+		} else {
+			where = append(where, "suspended_at IS NULL")
+		}
+	}
+
+	query := `SELECT ` + adminUserColumns + ` FROM users`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY id ASC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []AdminUser
+	for rows.Next() {
+		u, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func GetAdminUser(db *sql.DB, userID int64) (AdminUser, error) {
+	u, err := scanAdminUser(db.QueryRow(
+		`SELECT `+adminUserColumns+` FROM users WHERE id = $1`,
+		userID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return AdminUser{}, ErrNotFound
+		}
+		return AdminUser{}, err
+	}
+	return u, nil
+}
+
+// SuspendUser blocks a user from logging in and revokes their sessions. It
+// returns the revoked session IDs. Suspending again only updates the reason.
+func SuspendUser(db *sql.DB, userID int64, reason sql.NullString) ([]int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE users
+		 SET suspended_at = COALESCE(suspended_at, NOW()), suspension_reason = $1
+		 WHERE id = $2`,
+		reason,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	ids, err := revokeAllSessionsTx(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func UnsuspendUser(db *sql.DB, userID int64) error {
+	result, err := db.Exec(
+		`UPDATE users SET suspended_at = NULL, suspension_reason = NULL WHERE id = $1`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetUserRole changes a user's role and revokes their sessions, since access
+// tokens carry the role. It returns the revoked session IDs.
+func SetUserRole(db *sql.DB, userID int64, role string) ([]int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	ids, err := revokeAllSessionsTx(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+func CreateAPIToken(db *sql.DB, token models.APIToken) (models.APIToken, error) {
+	var created models.APIToken
+	err := db.QueryRow(
+		`INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, user_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, created_at`,
+		token.UserID,
+		token.Name,
+		token.TokenPrefix,
+		token.TokenHash,
+		pq.Array(token.Scopes),
+		token.ExpiresAt,
+	).Scan(&created.ID, &created.UserID, &created.Name, &created.TokenPrefix, &created.TokenHash, pq.Array(&created.Scopes), &created.ExpiresAt, &created.LastUsedAt, &created.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.APIToken{}, ErrDuplicate
+		}
+		return models.APIToken{}, err
+	}
+	return created, nil
+}
+
+func ListAPITokensByUser(db *sql.DB, userID int64) ([]models.APIToken, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, created_at
+		 FROM api_tokens
+		 WHERE user_id = $1
+		 ORDER BY created_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		var t models.APIToken
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenPrefix, &t.TokenHash, pq.Array(&t.Scopes), &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetActiveAPITokenByHash returns an unexpired token of an account that is not
+// suspended. last_used_at is bumped at most once a minute so busy scripts do
+// not write on every request.
+func GetActiveAPITokenByHash(db *sql.DB, tokenHash string) (models.APIToken, error) {
+	var token models.APIToken
+	err := db.QueryRow(
+		`SELECT t.id, t.user_id, t.name, t.token_prefix, t.token_hash, t.scopes, t.expires_at, t.last_used_at, t.created_at
+		 FROM api_tokens t
+		 JOIN users u ON u.id = t.user_id
+		 WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		   AND u.suspended_at IS NULL`,
+		tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.TokenHash, pq.Array(&token.Scopes), &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.APIToken{}, ErrNotFound
+		}
+		return models.APIToken{}, err
+	}
+
+	if _, err := db.Exec(
+		`UPDATE api_tokens
+		 SET last_used_at = NOW()
+		 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`,
+		token.ID,
+	); err != nil {
+		return models.APIToken{}, err
+	}
+	return token, nil
+}
+
+func DeleteAPITokenByIDAndUser(db *sql.DB, tokenID, userID int64) error {
+	result, err := db.Exec(
+		`DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`,
+		tokenID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
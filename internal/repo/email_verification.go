@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MarkEmailVerified verifies the address a verification link was issued for.
+// That is either the user's current email or a pending new one, which then
+// replaces the current email. It fails with ErrNotFound if the address is
+// neither any more, and ErrDuplicate if another account took it meanwhile.
+// emailCanonical is only stored when the email changes.
+func MarkEmailVerified(db *sql.DB, userID int64, email, emailCanonical string) error {
+	result, err := db.Exec(
+		`UPDATE users
+		 SET email_verified_at = CASE
+				WHEN email = $2 THEN COALESCE(email_verified_at, NOW())
+				ELSE NOW()
+			END,
+			email_canonical = CASE WHEN email = $2 THEN email_canonical ELSE $3 END,
+			email = $2,
+			pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END
+		 WHERE id = $1 AND (email = $2 OR pending_email = $2)`,
+		userID,
+		email,
+		emailCanonical,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimEmailVerificationSend records that a verification email is being sent,
+// unless one was already sent after notBefore. It reports whether the caller
+// may send.
+func ClaimEmailVerificationSend(db *sql.DB, userID int64, notBefore time.Time) (bool, error) {
+	result, err := db.Exec(
+		`UPDATE users
+		 SET email_verification_sent_at = NOW()
+		 WHERE id = $1
+		   AND (email_verification_sent_at IS NULL OR email_verification_sent_at <= $2)`,
+		userID,
+		notBefore,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func GetEmailVerificationSentAt(db *sql.DB, userID int64) (sql.NullTime, error) {
+	var sentAt sql.NullTime
+	err := db.QueryRow(
+		`SELECT email_verification_sent_at FROM users WHERE id = $1`,
+		userID,
+	).Scan(&sentAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullTime{}, ErrNotFound
+		}
+		return sql.NullTime{}, err
+	}
+	return sentAt, nil
+}
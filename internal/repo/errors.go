@@ -3,6 +3,7 @@ package repo
 import "errors"
 
 var (
-	ErrNotFound  = errors.New("not found")
-	ErrDuplicate = errors.New("duplicate")
+	ErrNotFound    = errors.New("not found")
+	ErrDuplicate   = errors.New("duplicate")
+	ErrTokenReused = errors.New("token reused")
 )
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+func ListTreesByUser(db *sql.DB, userID int64) ([]models.Tree, error) {
+	rows, err := db.Query(
+		`SELECT `+treeColumns+`
+		 FROM trees t
+		 WHERE t.user_id = $1
+		 ORDER BY t.id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trees []models.Tree
+	for rows.Next() {
+		tree, err := scanTree(rows)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// ListAllLinksByUser returns every link on every tree of the user, including
+// inactive ones.
+func ListAllLinksByUser(db *sql.DB, userID int64) ([]models.Link, error) {
+	rows, err := db.Query(
+		`SELECT l.id, l.tree_id, l.title, l.url, l.position, l.is_active, l.created_at
+		 FROM links l
+		 JOIN trees t ON l.tree_id = t.id
+		 WHERE t.user_id = $1
+		 ORDER BY l.tree_id ASC, l.position ASC, l.id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var link models.Link
+		if err := rows.Scan(&link.ID, &link.TreeID, &link.Title, &link.URL, &link.Position, &link.IsActive, &link.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ListAllSessionsByUser returns active and revoked sessions.
+func ListAllSessionsByUser(db *sql.DB, userID int64) ([]models.Session, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, device, ip_address, user_agent, last_seen_at, revoked_at, created_at
+		 FROM sessions
+		 WHERE user_id = $1
+		 ORDER BY created_at ASC, id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.IPAddress, &s.UserAgent, &s.LastSeenAt, &s.RevokedAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+func CreateOIDCState(db *sql.DB, state models.OIDCState) error {
+	if _, err := db.Exec(`DELETE FROM oidc_states WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO oidc_states (state, provider, nonce, code_verifier, user_id, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		state.State,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.UserID,
+		state.ExpiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// ConsumeOIDCState deletes and returns an unexpired authorization request.
+func ConsumeOIDCState(db *sql.DB, state string) (models.OIDCState, error) {
+	var s models.OIDCState
+	err := db.QueryRow(
+		`DELETE FROM oidc_states
+		 WHERE state = $1 AND expires_at > NOW()
+		 RETURNING state, provider, nonce, code_verifier, user_id, expires_at, created_at`,
+		state,
+	).Scan(&s.State, &s.Provider, &s.Nonce, &s.CodeVerifier, &s.UserID, &s.ExpiresAt, &s.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.OIDCState{}, ErrNotFound
+		}
+		return models.OIDCState{}, err
+	}
+	return s, nil
+}
+
+// GetIdentity looks up an identity and records the login.
+func GetIdentity(db *sql.DB, provider, subject string) (models.Identity, error) {
+	var identity models.Identity
+	err := db.QueryRow(
+		`UPDATE identities
+		 SET last_login_at = NOW()
+		 WHERE provider = $1 AND subject = $2
+		 RETURNING id, user_id, provider, subject, email, last_login_at, created_at`,
+		provider,
+		subject,
+	).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.LastLoginAt, &identity.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Identity{}, ErrNotFound
+		}
+		return models.Identity{}, err
+	}
+	return identity, nil
+}
+
+func CreateIdentity(db *sql.DB, userID int64, provider, subject string, email sql.NullString) (models.Identity, error) {
+	var identity models.Identity
+	err := db.QueryRow(
+		`INSERT INTO identities (user_id, provider, subject, email, last_login_at)
+		 VALUES ($1, $2, $3, $4, NOW())
+		 RETURNING id, user_id, provider, subject, email, last_login_at, created_at`,
+		userID,
+		provider,
+		subject,
+		email,
+	).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.LastLoginAt, &identity.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.Identity{}, ErrDuplicate
+		}
+		return models.Identity{}, err
+	}
+	return identity, nil
+}
+
+// CreateUserWithIdentity creates a passwordless account for a first-time
+// provider login. The email is stored only when the provider verified it, and
+// is then marked verified here too.
+func CreateUserWithIdentity(db *sql.DB, provider, subject string, verifiedEmail, emailCanonical sql.NullString) (models.User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	err = tx.QueryRow(
+		`INSERT INTO users (email, email_canonical, email_verified_at)
+		 VALUES ($1, $2, CASE WHEN $1::TEXT IS NULL THEN NULL ELSE NOW() END)
+		 RETURNING id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at`,
+		verifiedEmail,
+		emailCanonical,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.User{}, ErrDuplicate
+		}
+		return models.User{}, err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO identities (user_id, provider, subject, email, last_login_at)
+		 VALUES ($1, $2, $3, $4, NOW())`,
+		user.ID,
+		provider,
+		subject,
+		verifiedEmail,
+	); err != nil {
+		if isUniqueViolation(err) {
+			return models.User{}, ErrDuplicate
+		}
+		return models.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func ListIdentitiesByUser(db *sql.DB, userID int64) ([]models.Identity, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		 FROM identities
+		 WHERE user_id = $1
+		 ORDER BY created_at ASC, id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []models.Identity
+	for rows.Next() {
+		var i models.Identity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.LastLoginAt, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// DeleteIdentityByIDAndUser unlinks an identity unless it is the account's
+// only way to sign in.
+func DeleteIdentityByIDAndUser(db *sql.DB, identityID, userID int64) error {
+	result, err := db.Exec(
+		`DELETE FROM identities i
+		 USING users u
+		 WHERE i.user_id = u.id AND i.id = $1 AND u.id = $2
+		   AND (
+			u.password_hash IS NOT NULL
+			OR u.wallet_address IS NOT NULL
+			OR EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.user_id = u.id)
+			OR EXISTS (
+				SELECT 1 FROM identities o
+				WHERE o.user_id = u.id AND o.id <> i.id
+			)
+		   )`,
+		identityID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -6,33 +6,6 @@ import (
 	"chainhub-api/internal/models"
 )
 
-func ListActiveLinksByTreeID(db *sql.DB, treeID int64) ([]models.Link, error) {
-	rows, err := db.Query(
-		`SELECT id, tree_id, title, url, position, is_active, created_at
-		 FROM links
-		 WHERE tree_id = $1 AND is_active = TRUE
-		 ORDER BY position ASC, id ASC`,
-		treeID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var links []models.Link
-	for rows.Next() {
-		var link models.Link
-		if err := rows.Scan(&link.ID, &link.TreeID, &link.Title, &link.URL, &link.Position, &link.IsActive, &link.CreatedAt); err != nil {
-			return nil, err
-		}
-		links = append(links, link)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return links, nil
-}
-
 func CreateLink(db *sql.DB, treeID int64, title, url string, position int, isActive bool) (models.Link, error) {
 	var link models.Link
 	err := db.QueryRow(
@@ -51,15 +24,18 @@ func CreateLink(db *sql.DB, treeID int64, title, url string, position int, isAct
 	return link, nil
 }
 
-func CreateLinkByUser(db *sql.DB, userID int64, title, url string, position int, isActive bool) (models.Link, error) {
+// CreateLinkByUser adds a link to one of the user's trees. It returns
+// ErrNotFound if the tree is not theirs.
+func CreateLinkByUser(db *sql.DB, userID, treeID int64, title, url string, position int, isActive bool) (models.Link, error) {
 	var link models.Link
 	err := db.QueryRow(
 		`INSERT INTO links (tree_id, title, url, position, is_active)
-		 SELECT t.id, $2, $3, $4, $5
+		 SELECT t.id, $3, $4, $5, $6
 		 FROM trees t
-		 WHERE t.user_id = $1
+		 WHERE t.user_id = $1 AND t.id = $2
 		 RETURNING id, tree_id, title, url, position, is_active, created_at`,
 		userID,
+		treeID,
 		title,
 		url,
 		position,
@@ -103,60 +79,6 @@ func ListLinksByTreeIDAndUser(db *sql.DB, treeID, userID int64) ([]models.Link,
 	return links, nil
 }
 
-func ListLinksByUsernameAndUser(db *sql.DB, username string, userID int64) ([]models.Link, error) {
-	rows, err := db.Query(
-		`SELECT l.id, l.tree_id, l.title, l.url, l.position, l.is_active, l.created_at
-		 FROM trees t
-		 JOIN users u ON t.user_id = u.id
-		 LEFT JOIN links l ON l.tree_id = t.id
-		 WHERE t.user_id = $1 AND u.username = $2
-		 ORDER BY l.position ASC, l.id ASC`,
-		userID,
-		username,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var links []models.Link
-	found := false
-	for rows.Next() {
-		found = true
-
-		var linkID sql.NullInt64
-		var treeID sql.NullInt64
-		var title sql.NullString
-		var url sql.NullString
-		var position sql.NullInt64
-		var isActive sql.NullBool
-		var createdAt sql.NullTime
-
-		if err := rows.Scan(&linkID, &treeID, &title, &url, &position, &isActive, &createdAt); err != nil {
-			return nil, err
-		}
-
-		if linkID.Valid {
-			links = append(links, models.Link{
-				ID:        linkID.Int64,
-				TreeID:    treeID.Int64,
-				Title:     title.String,
-				URL:       url.String,
-				Position:  int(position.Int64),
-				IsActive:  isActive.Bool,
-				CreatedAt: createdAt.Time,
-			})
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	if !found {
-		return nil, ErrNotFound
-	}
-	return links, nil
-}
-
 func UpdateLinkByIDAndUser(db *sql.DB, linkID, userID int64, title, url string, position int, isActive bool) (models.Link, error) {
 	var link models.Link
 	err := db.QueryRow(
@@ -181,23 +103,22 @@ func UpdateLinkByIDAndUser(db *sql.DB, linkID, userID int64, title, url string,
 	return link, nil
 }
 
-func DeleteLinkByIDAndUser(db *sql.DB, linkID, userID int64) error {
-	result, err := db.Exec(
+// DeleteLinkByIDAndUser returns the ID of the tree the link was on.
+func DeleteLinkByIDAndUser(db *sql.DB, linkID, userID int64) (int64, error) {
+	var treeID int64
+	err := db.QueryRow(
 		`DELETE FROM links l
 		 USING trees t
-		 WHERE l.tree_id = t.id AND l.id = $1 AND t.user_id = $2`,
+		 WHERE l.tree_id = t.id AND l.id = $1 AND t.user_id = $2
+		 RETURNING l.tree_id`,
 		linkID,
 		userID,
-	)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
+	).Scan(&treeID)
 	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return ErrNotFound
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
 	}
-	return nil
+	return treeID, nil
 }
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetLoginThrottle returns a key's failure count and block, or zero values if
+// the key has none.
+func GetLoginThrottle(db *sql.DB, key string) (int, sql.NullTime, error) {
+	var failures int
+	var blockedUntil sql.NullTime
+	err := db.QueryRow(
+		`SELECT failures, blocked_until FROM login_throttles WHERE key = $1`,
+		key,
+	).Scan(&failures, &blockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, sql.NullTime{}, nil
+		}
+		return 0, sql.NullTime{}, err
+	}
+	return failures, blockedUntil, nil
+}
+
+// RecordLoginThrottleFailure atomically counts a failure, starting over if
+// the previous one was before resetBefore. Stale rows are cleaned up on the
+// way.
+func RecordLoginThrottleFailure(db *sql.DB, key string, resetBefore time.Time) (int, error) {
+	if _, err := db.Exec(
+		`DELETE FROM login_throttles
+		 WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until < NOW())
+		   AND key <> $2`,
+		resetBefore,
+		key,
+	); err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err := db.QueryRow(
+		`INSERT INTO login_throttles (key, failures, last_failure_at)
+		 VALUES ($1, 1, NOW())
+		 ON CONFLICT (key) DO UPDATE
+		 SET failures = CASE
+				WHEN login_throttles.last_failure_at < $2 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = NOW()
+		 RETURNING failures`,
+		key,
+		resetBefore,
+	).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func BlockLoginThrottle(db *sql.DB, key string, until time.Time) error {
+	_, err := db.Exec(
+		`UPDATE login_throttles
+		 SET blocked_until = GREATEST(blocked_until, $2)
+		 WHERE key = $1`,
+		key,
+		until,
+	)
+	return err
+}
+
+func DeleteLoginThrottle(db *sql.DB, key string) error {
+	_, err := db.Exec(`DELETE FROM login_throttles WHERE key = $1`, key)
+	return err
+}
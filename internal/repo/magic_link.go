@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreateMagicLink records a login link with the given token ID, unless the
+// user was already sent one after notBefore. It reports whether the caller
+// may send.
+func CreateMagicLink(db *sql.DB, userID int64, jti string, expiresAt, notBefore time.Time) (bool, error) {
+	result, err := db.Exec(
+		`INSERT INTO magic_link_tokens (user_id, jti, expires_at)
+		 SELECT $1, $2, $3
+		 WHERE NOT EXISTS (
+			SELECT 1 FROM magic_link_tokens
+			WHERE user_id = $1 AND created_at > $4
+		 )`,
+		userID,
+		jti,
+		expiresAt,
+		notBefore,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, ErrDuplicate
+		}
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ConsumeMagicLink redeems a login link. It fails with ErrNotFound if the link
+// was used or expired, or if the account's email is no longer the verified
+// address the link was sent to.
+func ConsumeMagicLink(db *sql.DB, userID int64, jti, email string) error {
+	result, err := db.Exec(
+		`UPDATE magic_link_tokens t
+		 SET used_at = NOW()
+		 FROM users u
+		 WHERE t.user_id = u.id
+		   AND t.jti = $1 AND t.user_id = $2
+		   AND t.used_at IS NULL AND t.expires_at > NOW()
+		   AND u.email = $3 AND u.email_verified_at IS NOT NULL`,
+		jti,
+		userID,
+		email,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+// CreatePendingTOTP stores a new, unconfirmed secret for the user, replacing
+// any earlier unconfirmed one. It returns ErrDuplicate if TOTP is already on.
+func CreatePendingTOTP(db *sql.DB, userID int64, secret string) error {
+	var id int64
+	err := db.QueryRow(
+		`INSERT INTO totp_credentials (user_id, secret)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = NOW()
+		 WHERE totp_credentials.confirmed_at IS NULL
+		 RETURNING user_id`,
+		userID,
+		secret,
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+func GetTOTPCredential(db *sql.DB, userID int64) (models.TOTPCredential, error) {
+	var cred models.TOTPCredential
+	err := db.QueryRow(
+		`SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		 FROM totp_credentials
+		 WHERE user_id = $1`,
+		userID,
+	).Scan(&cred.UserID, &cred.Secret, &cred.ConfirmedAt, &cred.LastUsedStep, &cred.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TOTPCredential{}, ErrNotFound
+		}
+		return models.TOTPCredential{}, err
+	}
+	return cred, nil
+}
+
+func IsTOTPEnabled(db *sql.DB, userID int64) (bool, error) {
+	var enabled bool
+	err := db.QueryRow(
+		`SELECT EXISTS(
+			SELECT 1 FROM totp_credentials WHERE user_id = $1 AND confirmed_at IS NOT NULL
+		)`,
+		userID,
+	).Scan(&enabled)
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+// ConfirmTOTP turns on a pending secret after the first valid code and
+// replaces the user's recovery codes.
+func ConfirmTOTP(db *sql.DB, userID, step int64, recoveryCodeHashes []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE totp_credentials
+		 SET confirmed_at = NOW(), last_used_step = $2
+		 WHERE user_id = $1 AND confirmed_at IS NULL`,
+		userID,
+		step,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID,
+			hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records a verified time step, refusing steps at or before the
+// last one used so a code cannot be replayed.
+func UseTOTPStep(db *sql.DB, userID, step int64) (bool, error) {
+	result, err := db.Exec(
+		`UPDATE totp_credentials
+		 SET last_used_step = $2
+		 WHERE user_id = $1
+		   AND confirmed_at IS NOT NULL
+		   AND (last_used_step IS NULL OR last_used_step < $2)`,
+		userID,
+		step,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func UseRecoveryCode(db *sql.DB, userID int64, codeHash string) (bool, error) {
+	result, err := db.Exec(
+		`UPDATE mfa_recovery_codes
+		 SET used_at = NOW()
+		 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func DeleteTOTP(db *sql.DB, userID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM totp_credentials WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+// GetOrCreateWebAuthnHandle returns the opaque WebAuthn user handle for a
+// user, storing newHandle the first time.
+func GetOrCreateWebAuthnHandle(db *sql.DB, userID int64, newHandle []byte) ([]byte, error) {
+	if _, err := db.Exec(
+		`INSERT INTO webauthn_user_handles (user_id, handle)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO NOTHING`,
+		userID,
+		newHandle,
+	); err != nil {
+		return nil, err
+	}
+
+	var handle []byte
+	err := db.QueryRow(
+		`SELECT handle FROM webauthn_user_handles WHERE user_id = $1`,
+		userID,
+	).Scan(&handle)
+	if err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+func GetUserIDByWebAuthnHandle(db *sql.DB, handle []byte) (int64, error) {
+	var userID int64
+	err := db.QueryRow(
+		`SELECT user_id FROM webauthn_user_handles WHERE handle = $1`,
+		handle,
+	).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+func CreatePasskey(db *sql.DB, passkey models.Passkey) (models.Passkey, error) {
+	var created models.Passkey
+	err := db.QueryRow(
+		`INSERT INTO webauthn_credentials
+		 (user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, name)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id, user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, name, last_used_at, created_at`,
+		passkey.UserID,
+		passkey.CredentialID,
+		passkey.PublicKey,
+		passkey.AttestationType,
+		passkey.Transports,
+		passkey.AAGUID,
+		passkey.SignCount,
+		passkey.BackupEligible,
+		passkey.BackupState,
+		passkey.Name,
+	).Scan(&created.ID, &created.UserID, &created.CredentialID, &created.PublicKey, &created.AttestationType, &created.Transports, &created.AAGUID, &created.SignCount, &created.BackupEligible, &created.BackupState, &created.Name, &created.LastUsedAt, &created.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.Passkey{}, ErrDuplicate
+		}
+		return models.Passkey{}, err
+	}
+	return created, nil
+}
+
+func ListPasskeysByUser(db *sql.DB, userID int64) ([]models.Passkey, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count, backup_eligible, backup_state, name, last_used_at, created_at
+		 FROM webauthn_credentials
+		 WHERE user_id = $1
+		 ORDER BY created_at ASC, id ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var passkeys []models.Passkey
+	for rows.Next() {
+		var p models.Passkey
+		if err := rows.Scan(&p.ID, &p.UserID, &p.CredentialID, &p.PublicKey, &p.AttestationType, &p.Transports, &p.AAGUID, &p.SignCount, &p.BackupEligible, &p.BackupState, &p.Name, &p.LastUsedAt, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+// RecordPasskeyUse stores the authenticator's new sign counter after a login.
+func RecordPasskeyUse(db *sql.DB, credentialID []byte, signCount int64, backupState bool) error {
+	_, err := db.Exec(
+		`UPDATE webauthn_credentials
+		 SET sign_count = $2, backup_state = $3, last_used_at = NOW()
+		 WHERE credential_id = $1`,
+		credentialID,
+		signCount,
+		backupState,
+	)
+	return err
+}
+
+// DeletePasskeyByIDAndUser removes a passkey unless it is the account's only
+// way to sign in.
+func DeletePasskeyByIDAndUser(db *sql.DB, passkeyID, userID int64) error {
+	result, err := db.Exec(
+		`DELETE FROM webauthn_credentials c
+		 USING users u
+		 WHERE c.user_id = u.id AND c.id = $1 AND u.id = $2
+		   AND (
+			u.password_hash IS NOT NULL
+			OR u.wallet_address IS NOT NULL
+			OR EXISTS (SELECT 1 FROM identities i WHERE i.user_id = u.id)
+			OR EXISTS (
+				SELECT 1 FROM webauthn_credentials o
+				WHERE o.user_id = u.id AND o.id <> c.id
+			)
+		   )`,
+		passkeyID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func CountPasskeysByUser(db *sql.DB, userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func CreateWebAuthnChallenge(db *sql.DB, challenge models.WebAuthnChallenge) error {
+	if _, err := db.Exec(`DELETE FROM webauthn_challenges WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO webauthn_challenges (id, user_id, ceremony, session_data, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		challenge.ID,
+		challenge.UserID,
+		challenge.Ceremony,
+		challenge.SessionData,
+		challenge.ExpiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// ConsumeWebAuthnChallenge deletes and returns an unexpired challenge for the
+// given ceremony. userID must match for registration; login challenges are
+// created before the user is known and have no user.
+func ConsumeWebAuthnChallenge(db *sql.DB, id, ceremony string, userID sql.NullInt64) (models.WebAuthnChallenge, error) {
+	var challenge models.WebAuthnChallenge
+	err := db.QueryRow(
+		`DELETE FROM webauthn_challenges
+		 WHERE id = $1 AND ceremony = $2 AND user_id IS NOT DISTINCT FROM $3 AND expires_at > NOW()
+		 RETURNING id, user_id, ceremony, session_data, expires_at, created_at`,
+		id,
+		ceremony,
+		userID,
+	).Scan(&challenge.ID, &challenge.UserID, &challenge.Ceremony, &challenge.SessionData, &challenge.ExpiresAt, &challenge.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.WebAuthnChallenge{}, ErrNotFound
+		}
+		return models.WebAuthnChallenge{}, err
+	}
+	return challenge, nil
+}
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"chainhub-api/internal/models"
+)
+
+func CreatePasswordResetToken(db *sql.DB, userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(
+		`INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		 VALUES ($1, $2, $3)`,
+		userID,
+		tokenHash,
+		expiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPasswordResetUser returns the user an unused, unexpired reset token is
+// for, without redeeming it.
+func GetPasswordResetUser(db *sql.DB, tokenHash string) (models.User, error) {
+	var user models.User
+	err := db.QueryRow(
+		`SELECT u.id, u.email, u.username, u.password_hash, u.wallet_address, u.email_verified_at, u.role, u.suspended_at, u.created_at
+		 FROM password_reset_tokens t
+		 JOIN users u ON u.id = t.user_id
+		 WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW()`,
+		tokenHash,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.User{}, ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// ResetPassword redeems a reset token, sets the new password hash, and revokes
+// every session and personal access token of the user, since whoever knew the
+// old password may have created either. It returns the user ID and the
+// revoked session IDs.
+func ResetPassword(db *sql.DB, tokenHash, passwordHash string) (int64, []int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRow(
+		`UPDATE password_reset_tokens
+		 SET used_at = NOW()
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING user_id`,
+		tokenHash,
+	).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, ErrNotFound
+		}
+		return 0, nil, err
+	}
+
+	// Any other outstanding links for this account are now stale.
+	if _, err := tx.Exec(
+		`UPDATE password_reset_tokens
+		 SET used_at = NOW()
+		 WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	); err != nil {
+		return 0, nil, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE users SET password_hash = $1 WHERE id = $2`,
+		passwordHash,
+		userID,
+	); err != nil {
+		return 0, nil, err
+	}
+
+	sessionIDs, err := revokeAllSessionsTx(tx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM api_tokens WHERE user_id = $1`, userID); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return userID, sessionIDs, nil
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"chainhub-api/internal/models"
+)
+
+func CreateRefreshToken(db *sql.DB, userID, sessionID int64, familyID, tokenHash string, expiresAt time.Time) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := db.QueryRow(
+		`INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, user_id, session_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at`,
+		userID,
+		sessionID,
+		familyID,
+		tokenHash,
+		expiresAt,
+	).Scan(&token.ID, &token.UserID, &token.SessionID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.RefreshToken{}, ErrDuplicate
+		}
+		return models.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken marks the token identified by oldHash as used and issues
+// its successor in the same family. Presenting a token that was already used
+// revokes the whole family, and the session it belongs to, and returns the
+// reused token along with ErrTokenReused.
+func RotateRefreshToken(db *sql.DB, oldHash, newHash string, expiresAt time.Time) (models.RefreshToken, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+	defer tx.Rollback()
+
+	var current models.RefreshToken
+	err = tx.QueryRow(
+		`SELECT id, user_id, session_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+		 FROM refresh_tokens
+		 WHERE token_hash = $1
+		 FOR UPDATE`,
+		oldHash,
+	).Scan(&current.ID, &current.UserID, &current.SessionID, &current.FamilyID, &current.TokenHash, &current.ExpiresAt, &current.UsedAt, &current.RevokedAt, &current.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.RefreshToken{}, ErrNotFound
+		}
+		return models.RefreshToken{}, err
+	}
+
+	if current.RevokedAt.Valid || !current.ExpiresAt.After(time.Now()) {
+		return models.RefreshToken{}, ErrNotFound
+	}
+
+	if current.UsedAt.Valid {
+		if err := revokeSessionTx(tx, current.SessionID); err != nil {
+			return models.RefreshToken{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return models.RefreshToken{}, err
+		}
+		return current, ErrTokenReused
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`,
+		current.ID,
+	); err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	var next models.RefreshToken
+	err = tx.QueryRow(
+		`INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, user_id, session_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at`,
+		current.UserID,
+		current.SessionID,
+		current.FamilyID,
+		newHash,
+		expiresAt,
+	).Scan(&next.ID, &next.UserID, &next.SessionID, &next.FamilyID, &next.TokenHash, &next.ExpiresAt, &next.UsedAt, &next.RevokedAt, &next.CreatedAt)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.RefreshToken{}, err
+	}
+	return next, nil
+}
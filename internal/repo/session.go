@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+func CreateSession(db *sql.DB, userID int64, device, ipAddress, userAgent string) (models.Session, error) {
+	var session models.Session
+	err := db.QueryRow(
+		`INSERT INTO sessions (user_id, device, ip_address, user_agent)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, user_id, device, ip_address, user_agent, last_seen_at, revoked_at, created_at`,
+		userID,
+		device,
+		ipAddress,
+		userAgent,
+	).Scan(&session.ID, &session.UserID, &session.Device, &session.IPAddress, &session.UserAgent, &session.LastSeenAt, &session.RevokedAt, &session.CreatedAt)
+	if err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func ListActiveSessionsByUser(db *sql.DB, userID int64) ([]models.Session, error) {
+	rows, err := db.Query(
+		`SELECT id, user_id, device, ip_address, user_agent, last_seen_at, revoked_at, created_at
+		 FROM sessions
+		 WHERE user_id = $1 AND revoked_at IS NULL
+		 ORDER BY last_seen_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Device, &session.IPAddress, &session.UserAgent, &session.LastSeenAt, &session.RevokedAt, &session.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchSession records activity on a session and reports whether it is still active.
+func TouchSession(db *sql.DB, sessionID int64) (bool, error) {
+	var revokedAt sql.NullTime
+	err := db.QueryRow(
+		`UPDATE sessions
+		 SET last_seen_at = NOW()
+		 WHERE id = $1
+		 RETURNING revoked_at`,
+		sessionID,
+	).Scan(&revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return !revokedAt.Valid, nil
+}
+
+func RevokeSessionByIDAndUser(db *sql.DB, sessionID, userID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(
+		`SELECT EXISTS(
+			SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		)`,
+		sessionID,
+		userID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	if err := revokeSessionTx(tx, sessionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeSessionByRefreshToken revokes the session a refresh token belongs to
+// and returns its ID.
+func RevokeSessionByRefreshToken(db *sql.DB, tokenHash string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sessionID int64
+	err = tx.QueryRow(
+		`SELECT session_id FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash,
+	).Scan(&sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	if err := revokeSessionTx(tx, sessionID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sessionID, nil
+}
+
+func revokeSessionTx(tx *sql.Tx, sessionID int64) error {
+	if _, err := tx.Exec(
+		`UPDATE sessions
+		 SET revoked_at = NOW()
+		 WHERE id = $1 AND revoked_at IS NULL`,
+		sessionID,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		`UPDATE refresh_tokens
+		 SET revoked_at = NOW()
+		 WHERE session_id = $1 AND revoked_at IS NULL`,
+		sessionID,
+	)
+	return err
+}
+
+// RevokeAllSessionsByUser signs a user out everywhere and returns the IDs of
+// the sessions that were revoked.
+func RevokeAllSessionsByUser(db *sql.DB, userID int64) ([]int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := revokeAllSessionsTx(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func revokeAllSessionsTx(tx *sql.Tx, userID int64) ([]int64, error) {
+	rows, err := tx.Query(
+		`UPDATE sessions
+		 SET revoked_at = NOW()
+		 WHERE user_id = $1 AND revoked_at IS NULL
+		 RETURNING id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE refresh_tokens
+		 SET revoked_at = NOW()
+		 WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+)
+
+func CreateSIWENonce(db *sql.DB, nonce string, expiresAt time.Time) error {
+	// Opportunistically clear out nonces that were never used.
+	if _, err := db.Exec(`DELETE FROM siwe_nonces WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO siwe_nonces (nonce, expires_at)
+		 VALUES ($1, $2)`,
+		nonce,
+		expiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// ConsumeSIWENonce deletes an unexpired nonce so it can only be used once.
+func ConsumeSIWENonce(db *sql.DB, nonce string) error {
+	result, err := db.Exec(
+		`DELETE FROM siwe_nonces
+		 WHERE nonce = $1 AND expires_at > NOW()`,
+		nonce,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
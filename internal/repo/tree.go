@@ -2,19 +2,28 @@ package repo
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 
 	"chainhub-api/internal/models"
 )
 
-func GetTreeByUsername(db *sql.DB, username string) (models.Tree, error) {
+const treeColumns = `t.id, t.user_id, t.title, t.slug, t.is_default, t.display_name, t.bio, t.avatar_url, t.socials, t.theme_preset, t.theme_overrides, t.published_at, t.auto_publish, t.created_at, t.updated_at`
+
+func scanTree(row interface{ Scan(...interface{}) error }) (models.Tree, error) {
 	var tree models.Tree
-	err := db.QueryRow(
-		`SELECT t.id, t.user_id, t.title, t.created_at
-		 FROM trees t
-		 JOIN users u ON t.user_id = u.id
-		 WHERE u.username = $1`,
-		username,
-	).Scan(&tree.ID, &tree.UserID, &tree.Title, &tree.CreatedAt)
+	var socials []byte
+	if err := row.Scan(&tree.ID, &tree.UserID, &tree.Title, &tree.Slug, &tree.IsDefault, &tree.DisplayName, &tree.Bio, &tree.AvatarURL, &socials, &tree.ThemePreset, &tree.ThemeOverrides, &tree.PublishedAt, &tree.AutoPublish, &tree.CreatedAt, &tree.UpdatedAt); err != nil {
+		return models.Tree{}, err
+	}
+	if err := json.Unmarshal(socials, &tree.Socials); err != nil {
+		return models.Tree{}, fmt.Errorf("tree %d socials: %w", tree.ID, err)
+	}
+	return tree, nil
+}
+
+func getTree(db *sql.DB, query string, args ...interface{}) (models.Tree, error) {
+	tree, err := scanTree(db.QueryRow(query, args...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Tree{}, ErrNotFound
@@ -24,6 +33,39 @@ func GetTreeByUsername(db *sql.DB, username string) (models.Tree, error) {
 	return tree, nil
 }
 
+// GetTreeByUsername returns the user's default tree.
+func GetTreeByUsername(db *sql.DB, username string) (models.Tree, error) {
+	return getTree(db,
+		`SELECT `+treeColumns+`
+		 FROM trees t
+		 JOIN users u ON t.user_id = u.id
+		 WHERE LOWER(u.username) = LOWER($1) AND t.is_default`,
+		username,
+	)
+}
+
+func GetTreeBySlug(db *sql.DB, slug string) (models.Tree, error) {
+	return getTree(db,
+		`SELECT `+treeColumns+` FROM trees t WHERE t.slug = $1`,
+		slug,
+	)
+}
+
+func GetTreeByIDAndUser(db *sql.DB, treeID, userID int64) (models.Tree, error) {
+	return getTree(db,
+		`SELECT `+treeColumns+` FROM trees t WHERE t.id = $1 AND t.user_id = $2`,
+		treeID,
+		userID,
+	)
+}
+
+func GetDefaultTree(db *sql.DB, userID int64) (models.Tree, error) {
+	return getTree(db,
+		`SELECT `+treeColumns+` FROM trees t WHERE t.user_id = $1 AND t.is_default`,
+		userID,
+	)
+}
+
 func TreeBelongsToUser(db *sql.DB, treeID, userID int64) (bool, error) {
 	var exists bool
 	err := db.QueryRow(
@@ -39,20 +81,141 @@ func TreeBelongsToUser(db *sql.DB, treeID, userID int64) (bool, error) {
 	return exists, nil
 }
 
-func CreateTree(db *sql.DB, userID int64, title string) (models.Tree, error) {
-	var tree models.Tree
-	err := db.QueryRow(
-		`INSERT INTO trees (user_id, title)
-		 VALUES ($1, $2)
-		 RETURNING id, user_id, title, created_at`,
+func CountTreesByUser(db *sql.DB, userID int64) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM trees WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// CreateTree creates a tree and publishes it as it starts out, empty. The
+// tree becomes the user's default if they have none; the user's row is locked
+// while that is decided, so concurrent creates cannot both claim it. It
+// returns ErrDuplicate when the slug is taken.
+func CreateTree(db *sql.DB, userID int64, title, slug string) (models.Tree, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tree{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return models.Tree{}, err
+	}
+
+	tree, err := scanTree(tx.QueryRow(
+		`INSERT INTO trees AS t (user_id, title, slug, is_default)
+		 VALUES ($1, $2, $3, NOT EXISTS (SELECT 1 FROM trees WHERE user_id = $1 AND is_default))
+		 RETURNING `+treeColumns,
 		userID,
 		title,
-	).Scan(&tree.ID, &tree.UserID, &tree.Title, &tree.CreatedAt)
+		slug,
+	))
 	if err != nil {
 		if isUniqueViolation(err) {
 			return models.Tree{}, ErrDuplicate
 		}
 		return models.Tree{}, err
 	}
+
+	tree, err = publishSnapshotTx(tx, tree.ID, newTreeSnapshot(tree, nil), userID, models.TreeVersionCreate)
+	if err != nil {
+		return models.Tree{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Tree{}, err
+	}
 	return tree, nil
 }
+
+// UpdateTree saves a tree's title, slug, profile, theme and auto-publish
+// setting. With makeDefault it also
+// becomes the user's default tree in place of the current one; a tree stops
+// being the default only by another taking over. It returns ErrDuplicate when
+// the slug is taken.
+func UpdateTree(db *sql.DB, tree models.Tree, makeDefault bool) (models.Tree, error) {
+	socials, err := json.Marshal(nonNilSocials(tree.Socials))
+	if err != nil {
+		return models.Tree{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tree{}, err
+	}
+	defer tx.Rollback()
+
+	if makeDefault {
+		if _, err := tx.Exec(
+			`UPDATE trees SET is_default = FALSE, updated_at = NOW()
+			 WHERE user_id = $1 AND is_default AND id <> $2`,
+			tree.UserID,
+			tree.ID,
+		); err != nil {
+			return models.Tree{}, err
+		}
+	}
+
+	updated, err := scanTree(tx.QueryRow(
+		`UPDATE trees t
+		 SET title = $1, slug = $2, is_default = t.is_default OR $3,
+			display_name = $4, bio = $5, avatar_url = $6, socials = $7,
+			theme_preset = $8, theme_overrides = $9, auto_publish = $10, updated_at = NOW()
+		 WHERE t.id = $11 AND t.user_id = $12
+		 RETURNING `+treeColumns,
+		tree.Title,
+		tree.Slug,
+		makeDefault,
+		tree.DisplayName,
+		tree.Bio,
+		tree.AvatarURL,
+		string(socials),
+		tree.ThemePreset,
+		string(tree.ThemeOverrides),
+		tree.AutoPublish,
+		tree.ID,
+		tree.UserID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return models.Tree{}, ErrDuplicate
+		}
+		return models.Tree{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Tree{}, err
+	}
+	return updated, nil
+}
+
+// nonNilSocials keeps an empty list stored as [] rather than null.
+func nonNilSocials(socials []models.TreeSocial) []models.TreeSocial {
+	if socials == nil {
+		return []models.TreeSocial{}
+	}
+	return socials
+}
+
+// DeleteTree deletes a tree and its links. The default tree is never deleted;
+// asking to is reported as ErrNotFound.
+func DeleteTree(db *sql.DB, treeID, userID int64) error {
+	result, err := db.Exec(
+		`DELETE FROM trees WHERE id = $1 AND user_id = $2 AND NOT is_default`,
+		treeID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"chainhub-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetTreeDraft returns the tree's working copy, as publishing it now would
+// store it.
+func GetTreeDraft(db *sql.DB, tree models.Tree) (models.TreeSnapshot, error) {
+	links, err := ListLinksByTreeIDAndUser(db, tree.ID, tree.UserID)
+	if err != nil {
+		return models.TreeSnapshot{}, err
+	}
+	return newTreeSnapshot(tree, links), nil
+}
+
+// GetPublishedTree returns what the tree's public page shows. It returns
+// ErrNotFound if the tree was never published.
+func GetPublishedTree(db *sql.DB, treeID int64) (models.TreeSnapshot, error) {
+	var raw []byte
+	err := db.QueryRow(`SELECT published FROM trees WHERE id = $1`, treeID).Scan(&raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TreeSnapshot{}, ErrNotFound
+		}
+		return models.TreeSnapshot{}, err
+	}
+	if raw == nil {
+		return models.TreeSnapshot{}, ErrNotFound
+	}
+	return decodeTreeSnapshot(treeID, raw)
+}
+
+// PublishTree replaces the public version of the tree with its working copy.
+// The tree row is locked while the copy is taken, so concurrent edits land
+// either wholly before or wholly after it.
+func PublishTree(db *sql.DB, treeID, userID int64) (models.Tree, error) {
+	return publishTree(db, treeID, userID, false)
+}
+
+// PublishTreeIfAuto publishes the tree only if it has auto-publish on. It
+// returns ErrNotFound otherwise.
+func PublishTreeIfAuto(db *sql.DB, treeID, userID int64) (models.Tree, error) {
+	return publishTree(db, treeID, userID, true)
+}
+
+func publishTree(db *sql.DB, treeID, userID int64, onlyIfAuto bool) (models.Tree, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tree{}, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + treeColumns + ` FROM trees t WHERE t.id = $1 AND t.user_id = $2`
+	if onlyIfAuto {
+		query += ` AND t.auto_publish`
+	}
+	tree, err := scanTree(tx.QueryRow(query+` FOR UPDATE`, treeID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		return models.Tree{}, err
+	}
+
+	links, err := listLinksTx(tx, treeID)
+	if err != nil {
+		return models.Tree{}, err
+	}
+	action := models.TreeVersionPublish
+	if onlyIfAuto {
+		action = models.TreeVersionAutoPublish
+	}
+	published, err := publishSnapshotTx(tx, treeID, newTreeSnapshot(tree, links), userID, action)
+	if err != nil {
+		return models.Tree{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Tree{}, err
+	}
+	return published, nil
+}
+
+// DiscardTreeDraft throws away unpublished changes, putting the tree's fields
+// and links back as they were last published.
+func DiscardTreeDraft(db *sql.DB, treeID, userID int64) (models.Tree, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tree{}, err
+	}
+	defer tx.Rollback()
+
+	var raw []byte
+	err = tx.QueryRow(
+		`SELECT published FROM trees WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		treeID,
+		userID,
+	).Scan(&raw)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		return models.Tree{}, err
+	}
+	if raw == nil {
+		return models.Tree{}, ErrNotFound
+	}
+	snapshot, err := decodeTreeSnapshot(treeID, raw)
+	if err != nil {
+		return models.Tree{}, err
+	}
+
+	tree, err := restoreSnapshotTx(tx, treeID, snapshot)
+	if err != nil {
+		return models.Tree{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Tree{}, err
+	}
+	return tree, nil
+}
+
+// publishSnapshotTx makes snapshot the tree's public version and records it
+// as the tree's next version. Updating the tree row first holds its lock
+// until commit, so version numbers cannot be taken twice.
+func publishSnapshotTx(tx *sql.Tx, treeID int64, snapshot models.TreeSnapshot, authorID int64, action string) (models.Tree, error) {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return models.Tree{}, err
+	}
+	tree, err := scanTree(tx.QueryRow(
+		`UPDATE trees t SET published = $1, published_at = NOW()
+		 WHERE t.id = $2
+		 RETURNING `+treeColumns,
+		string(raw),
+		treeID,
+	))
+	if err != nil {
+		return models.Tree{}, err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO tree_versions (tree_id, version, snapshot, action, author_id, created_at)
+		 SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		 FROM tree_versions
+		 WHERE tree_id = $1`,
+		treeID,
+		string(raw),
+		action,
+		authorID,
+		tree.PublishedAt,
+	); err != nil {
+		return models.Tree{}, err
+	}
+	return tree, nil
+}
+
+// restoreSnapshotTx makes the tree's working copy match snapshot. Links keep
+// their IDs: ones the snapshot lacks are deleted, deleted ones it has are
+// recreated under their old ID, and the rest are overwritten.
+func restoreSnapshotTx(tx *sql.Tx, treeID int64, snapshot models.TreeSnapshot) (models.Tree, error) {
+	socials, err := json.Marshal(nonNilSocials(snapshot.Socials))
+	if err != nil {
+		return models.Tree{}, err
+	}
+	overrides := string(snapshot.ThemeOverrides)
+	if overrides == "" {
+		overrides = "{}"
+	}
+
+	tree, err := scanTree(tx.QueryRow(
+		`UPDATE trees t
+		 SET title = $1, display_name = $2, bio = $3, avatar_url = $4, socials = $5,
+			theme_preset = $6, theme_overrides = $7, updated_at = NOW()
+		 WHERE t.id = $8
+		 RETURNING `+treeColumns,
+		snapshot.Title,
+		snapshot.DisplayName,
+		snapshot.Bio,
+		snapshot.AvatarURL,
+		string(socials),
+		snapshot.ThemePreset,
+		overrides,
+		treeID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		return models.Tree{}, err
+	}
+
+	ids := make([]int64, 0, len(snapshot.Links))
+	for _, link := range snapshot.Links {
+		ids = append(ids, link.ID)
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM links WHERE tree_id = $1 AND NOT (id = ANY($2))`,
+		treeID,
+		pq.Array(ids),
+	); err != nil {
+		return models.Tree{}, err
+	}
+
+	for _, link := range snapshot.Links {
+		result, err := tx.Exec(
+			`INSERT INTO links (id, tree_id, title, url, position, is_active)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 ON CONFLICT (id) DO UPDATE
+			 SET title = EXCLUDED.title, url = EXCLUDED.url, position = EXCLUDED.position,
+				is_active = EXCLUDED.is_active, updated_at = NOW()
+			 WHERE links.tree_id = EXCLUDED.tree_id`,
+			link.ID,
+			treeID,
+			link.Title,
+			link.URL,
+			link.Position,
+			link.IsActive,
+		)
+		if err != nil {
+			return models.Tree{}, err
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return models.Tree{}, err
+		} else if rows == 0 {
+			return models.Tree{}, fmt.Errorf("tree %d: link %d belongs to another tree", treeID, link.ID)
+		}
+	}
+	return tree, nil
+}
+
+func listLinksTx(tx *sql.Tx, treeID int64) ([]models.Link, error) {
+	rows, err := tx.Query(
+		`SELECT id, tree_id, title, url, position, is_active, created_at
+		 FROM links
+		 WHERE tree_id = $1
+		 ORDER BY position ASC, id ASC`,
+		treeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var link models.Link
+		if err := rows.Scan(&link.ID, &link.TreeID, &link.Title, &link.URL, &link.Position, &link.IsActive, &link.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func newTreeSnapshot(tree models.Tree, links []models.Link) models.TreeSnapshot {
+	overrides := json.RawMessage(tree.ThemeOverrides)
+	if len(overrides) == 0 {
+		overrides = json.RawMessage("{}")
+	}
+	snapshot := models.TreeSnapshot{
+		Title:          tree.Title,
+		DisplayName:    tree.DisplayName,
+		Bio:            tree.Bio,
+		AvatarURL:      tree.AvatarURL,
+		Socials:        nonNilSocials(tree.Socials),
+		ThemePreset:    tree.ThemePreset,
+		ThemeOverrides: overrides,
+		Links:          make([]models.SnapshotLink, 0, len(links)),
+	}
+	for _, link := range links {
+		snapshot.Links = append(snapshot.Links, models.SnapshotLink{
+			ID:       link.ID,
+			Title:    link.Title,
+			URL:      link.URL,
+			Position: link.Position,
+			IsActive: link.IsActive,
+		})
+	}
+	return snapshot
+}
+
+func decodeTreeSnapshot(treeID int64, raw []byte) (models.TreeSnapshot, error) {
+	var snapshot models.TreeSnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return models.TreeSnapshot{}, fmt.Errorf("tree %d snapshot: %w", treeID, err)
+	}
+	if snapshot.Socials == nil {
+		snapshot.Socials = []models.TreeSocial{}
+	}
+	if snapshot.Links == nil {
+		snapshot.Links = []models.SnapshotLink{}
+	}
+	return snapshot, nil
+}
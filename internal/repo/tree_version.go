@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+func scanTreeVersion(row interface{ Scan(...interface{}) error }) (models.TreeVersion, error) {
+	var v models.TreeVersion
+	var raw []byte
+	if err := row.Scan(&v.ID, &v.TreeID, &v.Version, &raw, &v.Action, &v.AuthorID, &v.CreatedAt); err != nil {
+		return models.TreeVersion{}, err
+	}
+	snapshot, err := decodeTreeSnapshot(v.TreeID, raw)
+	if err != nil {
+		return models.TreeVersion{}, err
+	}
+	v.Snapshot = snapshot
+	return v, nil
+}
+
+// ListTreeVersions returns the tree's versions, newest first.
+func ListTreeVersions(db *sql.DB, treeID int64, limit, offset int) ([]models.TreeVersion, error) {
+	rows, err := db.Query(
+		`SELECT id, tree_id, version, snapshot, action, author_id, created_at
+		 FROM tree_versions
+		 WHERE tree_id = $1
+		 ORDER BY version DESC
+		 LIMIT $2 OFFSET $3`,
+		treeID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.TreeVersion
+	for rows.Next() {
+		v, err := scanTreeVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func GetTreeVersion(db *sql.DB, treeID int64, version int) (models.TreeVersion, error) {
+	v, err := scanTreeVersion(db.QueryRow(
+		`SELECT id, tree_id, version, snapshot, action, author_id, created_at
+		 FROM tree_versions
+		 WHERE tree_id = $1 AND version = $2`,
+		treeID,
+		version,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TreeVersion{}, ErrNotFound
+		}
+		return models.TreeVersion{}, err
+	}
+	return v, nil
+}
+
+// LatestTreeVersion returns the number of the tree's newest version, which is
+// what is published. It returns ErrNotFound if the tree has none.
+func LatestTreeVersion(db *sql.DB, treeID int64) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(
+		`SELECT MAX(version) FROM tree_versions WHERE tree_id = $1`,
+		treeID,
+	).Scan(&version); err != nil {
+		return 0, err
+	}
+	if !version.Valid {
+		return 0, ErrNotFound
+	}
+	return int(version.Int64), nil
+}
+
+// RestoreTreeVersion replaces the tree's draft, links included, with the
+// given version. With publish it is also published, as a new version; the
+// versions in between are kept. It returns ErrNotFound if the tree is not the
+// user's or has no such version.
+func RestoreTreeVersion(db *sql.DB, treeID, userID int64, version int, publish bool) (models.Tree, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return models.Tree{}, err
+	}
+	defer tx.Rollback()
+
+	var locked int64
+	if err := tx.QueryRow(
+		`SELECT id FROM trees WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		treeID,
+		userID,
+	).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		return models.Tree{}, err
+	}
+
+	v, err := scanTreeVersion(tx.QueryRow(
+		`SELECT id, tree_id, version, snapshot, action, author_id, created_at
+		 FROM tree_versions
+		 WHERE tree_id = $1 AND version = $2`,
+		treeID,
+		version,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Tree{}, ErrNotFound
+		}
+		return models.Tree{}, err
+	}
+
+	tree, err := restoreSnapshotTx(tx, treeID, v.Snapshot)
+	if err != nil {
+		return models.Tree{}, err
+	}
+	if publish {
+		links, err := listLinksTx(tx, treeID)
+		if err != nil {
+			return models.Tree{}, err
+		}
+		tree, err = publishSnapshotTx(tx, treeID, newTreeSnapshot(tree, links), userID, models.TreeVersionRestore)
+		if err != nil {
+			return models.Tree{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Tree{}, err
+	}
+	return tree, nil
+}
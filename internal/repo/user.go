@@ -2,24 +2,33 @@ package repo
 
 import (
 	"database/sql"
+	"time"
 
 	"chainhub-api/internal/models"
 
 	"github.com/lib/pq"
 )
 
-func CreateUser(db *sql.DB, email, username, passwordHash string) (models.User, error) {
+// CreateUser fails with ErrDuplicate if the email or username is taken,
+// including usernames that look like one still reserved by a rename redirect.
+func CreateUser(db *sql.DB, email, emailCanonical, username, usernameSkeleton, passwordHash string) (models.User, error) {
 	var user models.User
 	err := db.QueryRow(
-		`INSERT INTO users (email, username, password_hash)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, email, username, password_hash, wallet_address, created_at`,
+		`INSERT INTO users (email, email_canonical, username, username_skeleton, password_hash)
+		 SELECT $1, $2, $3, $4, $5
+		 WHERE NOT EXISTS (
+			SELECT 1 FROM username_redirects
+			WHERE (skeleton = $4 OR LOWER(old_username) = LOWER($3)) AND expires_at > NOW()
+		 )
+		 RETURNING id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at`,
 		email,
+		emailCanonical,
 		username,
+		usernameSkeleton,
 		passwordHash,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
 	if err != nil {
-		if isUniqueViolation(err) {
+		if err == sql.ErrNoRows || isUniqueViolation(err) {
 			return models.User{}, ErrDuplicate
 		}
 		return models.User{}, err
@@ -27,14 +36,31 @@ func CreateUser(db *sql.DB, email, username, passwordHash string) (models.User,
 	return user, nil
 }
 
+func GetUserByID(db *sql.DB, id int64) (models.User, error) {
+	var user models.User
+	err := db.QueryRow(
+		`SELECT id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at
+		 FROM users
+		 WHERE id = $1`,
+		id,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.User{}, ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
 func GetUserByEmail(db *sql.DB, email string) (models.User, error) {
 	var user models.User
 	err := db.QueryRow(
-		`SELECT id, email, username, password_hash, wallet_address, created_at
+		`SELECT id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at
 		 FROM users
-		 WHERE email = $1`,
+		 WHERE LOWER(email) = LOWER($1)`,
 		email,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.User{}, ErrNotFound
@@ -47,11 +73,11 @@ func GetUserByEmail(db *sql.DB, email string) (models.User, error) {
 func GetUserByEmailOrUsername(db *sql.DB, identifier string) (models.User, error) {
 	var user models.User
 	err := db.QueryRow(
-		`SELECT id, email, username, password_hash, wallet_address, created_at
+		`SELECT id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at
 		 FROM users
-		 WHERE email = $1 OR username = $1`,
+		 WHERE LOWER(email) = LOWER($1) OR LOWER(username) = LOWER($1)`,
 		identifier,
-	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.User{}, ErrNotFound
@@ -61,6 +87,158 @@ func GetUserByEmailOrUsername(db *sql.DB, identifier string) (models.User, error
 	return user, nil
 }
 
+// EmailTaken reports whether an account other than userID uses email, or an
+// address with the same canonical form.
+func EmailTaken(db *sql.DB, email, emailCanonical string, userID int64) (bool, error) {
+	var taken bool
+	err := db.QueryRow(
+		`SELECT EXISTS (
+			SELECT 1 FROM users
+			WHERE (LOWER(email) = LOWER($1) OR email_canonical = $2) AND id <> $3
+		 )`,
+		email,
+		emailCanonical,
+		userID,
+	).Scan(&taken)
+	if err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+// UserEmail is an account's email as stored, for the duplicate check run
+// before email uniqueness became case-insensitive.
+type UserEmail struct {
+	UserID    int64
+	Email     string
+	CreatedAt time.Time
+}
+
+func ListUserEmails(db *sql.DB) ([]UserEmail, error) {
+	rows, err := db.Query(
+		`SELECT id, email, created_at
+		 FROM users
+		 WHERE email IS NOT NULL
+		 ORDER BY created_at ASC, id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []UserEmail
+	for rows.Next() {
+		var e UserEmail
+		if err := rows.Scan(&e.UserID, &e.Email, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		emails = append(emails, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// GroupUserEmails returns the groups of more than one account whose emails
+// have the same key, in the order their first account was created.
+func GroupUserEmails(emails []UserEmail, key func(string) string) [][]UserEmail {
+	return groupDuplicates(emails, func(e UserEmail) string { return key(e.Email) })
+}
+
+// UserName is an account's username as stored, for the duplicate checks run
+// before usernames became unique regardless of case and look-alikes.
+type UserName struct {
+	UserID    int64
+	Username  string
+	CreatedAt time.Time
+}
+
+func ListUsernames(db *sql.DB) ([]UserName, error) {
+	rows, err := db.Query(
+		`SELECT id, username, created_at
+		 FROM users
+		 WHERE username IS NOT NULL
+		 ORDER BY created_at ASC, id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []UserName
+	for rows.Next() {
+		var n UserName
+		if err := rows.Scan(&n.UserID, &n.Username, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// GroupUsernames is GroupUserEmails for usernames.
+func GroupUsernames(names []UserName, key func(string) string) [][]UserName {
+	return groupDuplicates(names, func(n UserName) string { return key(n.Username) })
+}
+
+func groupDuplicates[T any](items []T, key func(T) string) [][]T {
+	groups := make(map[string][]T)
+	var order []string
+	for _, item := range items {
+		k := key(item)
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], item)
+	}
+
+	var duplicates [][]T
+	for _, k := range order {
+		if len(groups[k]) > 1 {
+			duplicates = append(duplicates, groups[k])
+		}
+	}
+	return duplicates
+}
+
+func GetUserByWalletAddress(db *sql.DB, walletAddress string) (models.User, error) {
+	var user models.User
+	err := db.QueryRow(
+		`SELECT id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at
+		 FROM users
+		 WHERE wallet_address = $1`,
+		walletAddress,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.User{}, ErrNotFound
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func CreateUserWithWallet(db *sql.DB, walletAddress string) (models.User, error) {
+	var user models.User
+	err := db.QueryRow(
+		`INSERT INTO users (wallet_address)
+		 VALUES ($1)
+		 RETURNING id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at`,
+		walletAddress,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.User{}, ErrDuplicate
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
 func isUniqueViolation(err error) bool {
 	if pgErr, ok := err.(*pq.Error); ok {
 		return pgErr.Code == "23505"
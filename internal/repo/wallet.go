@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"database/sql"
+
+	"chainhub-api/internal/models"
+)
+
+func CreateWalletChallenge(db *sql.DB, challenge models.WalletChallenge) error {
+	if _, err := db.Exec(`DELETE FROM wallet_challenges WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO wallet_challenges (nonce, user_id, wallet_address, message, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		challenge.Nonce,
+		challenge.UserID,
+		challenge.WalletAddress,
+		challenge.Message,
+		challenge.ExpiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// ConsumeWalletChallenge deletes and returns an unexpired challenge issued to userID.
+func ConsumeWalletChallenge(db *sql.DB, userID int64, nonce string) (models.WalletChallenge, error) {
+	var challenge models.WalletChallenge
+	err := db.QueryRow(
+		`DELETE FROM wallet_challenges
+		 WHERE nonce = $1 AND user_id = $2 AND expires_at > NOW()
+		 RETURNING nonce, user_id, wallet_address, message, expires_at, created_at`,
+		nonce,
+		userID,
+	).Scan(&challenge.Nonce, &challenge.UserID, &challenge.WalletAddress, &challenge.Message, &challenge.ExpiresAt, &challenge.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.WalletChallenge{}, ErrNotFound
+		}
+		return models.WalletChallenge{}, err
+	}
+	return challenge, nil
+}
+
+// LinkWalletToUser sets the wallet on an account that does not have one yet.
+func LinkWalletToUser(db *sql.DB, userID int64, walletAddress string) (models.User, error) {
+	var user models.User
+	err := db.QueryRow(
+		`UPDATE users
+		 SET wallet_address = $1
+		 WHERE id = $2 AND wallet_address IS NULL
+		 RETURNING id, email, username, password_hash, wallet_address, email_verified_at, role, suspended_at, created_at`,
+		walletAddress,
+		userID,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.WalletAddress, &user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.User{}, ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return models.User{}, ErrDuplicate
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// UnlinkWalletFromUser clears the wallet only while a password, passkey or
+// provider login remains.
+func UnlinkWalletFromUser(db *sql.DB, userID int64, walletAddress string) error {
+	result, err := db.Exec(
+		`UPDATE users
+		 SET wallet_address = NULL
+		 WHERE id = $1 AND wallet_address = $2
+		   AND (
+			password_hash IS NOT NULL
+			OR EXISTS (SELECT 1 FROM webauthn_credentials WHERE user_id = users.id)
+			OR EXISTS (SELECT 1 FROM identities WHERE user_id = users.id)
+		   )`,
+		userID,
+		walletAddress,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"chainhub-api/internal/repo"
+)
+
+// RunAccountDeletion hard-deletes accounts whose deletion grace period has
+// ended, once per interval until ctx is done. Several replicas may run it at
+// the same time; the delete is idempotent.
+func RunAccountDeletion(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteDueAccounts(db)
+		if err != nil {
+			log.Printf("account deletion: %v", err)
+		} else if deleted > 0 {
+			log.Printf("account deletion: deleted %d accounts", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
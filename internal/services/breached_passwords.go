@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// breachedLineMax bounds a corpus line: 40 hex characters, a colon and a count.
+const breachedLineMax = 64
+
+// BreachedPasswords looks passwords up in a local copy of the Have I Been Pwned
+// "SHA-1, ordered by hash" list, one HASH:COUNT line per breached password.
+// Like the k-anonymity range API, a lookup only reads the lines sharing the
+// first five characters of the password's hash, which it finds by binary
+// search, so the multi-gigabyte file never has to be loaded.
+type BreachedPasswords struct {
+	file *os.File
+	size int64
+}
+
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &BreachedPasswords{file: file, size: info.Size()}, nil
+}
+
+// Contains reports whether password appears in the corpus.
+func (b *BreachedPasswords) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	offset, err := b.findRange(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	for offset < b.size {
+		line, next, err := b.lineAt(offset)
+		if err != nil {
+			return false, err
+		}
+		lineHash, _, _ := strings.Cut(line, ":")
+		lineHash = strings.ToUpper(lineHash)
+		if !strings.HasPrefix(lineHash, prefix) {
+			return false, nil
+		}
+		if lineHash[len(prefix):] == suffix {
+			return true, nil
+		}
+		offset = next
+	}
+	return false, nil
+}
+
+func (b *BreachedPasswords) Close() error {
+	return b.file.Close()
+}
+
+// findRange returns the offset of the first line whose hash is not below
+// prefix.
+func (b *BreachedPasswords) findRange(prefix string) (int64, error) {
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := b.lineStart(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+		line, next, err := b.lineAt(start)
+		if err != nil {
+			return 0, err
+		}
+		if strings.ToUpper(line) < prefix {
+			lo = next
+		} else {
+			hi = start
+		}
+	}
+	return lo, nil
+}
+
+// lineStart returns the offset of the first line starting at or after offset.
+func (b *BreachedPasswords) lineStart(offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	buf := make([]byte, breachedLineMax)
+	n, err := b.file.ReadAt(buf, offset-1)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	i := bytes.IndexByte(buf[:n], '\n')
+	if i < 0 {
+		return b.size, nil
+	}
+	return offset + int64(i), nil
+}
+
+// lineAt returns the line at offset without its line ending, and the offset of
+// the next line.
+func (b *BreachedPasswords) lineAt(offset int64) (string, int64, error) {
+	buf := make([]byte, breachedLineMax)
+	n, err := b.file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	buf = buf[:n]
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		if offset+int64(n) < b.size {
+			return "", 0, fmt.Errorf("breached password corpus: line at offset %d is too long", offset)
+		}
+		i = n
+	}
+	return strings.TrimRight(string(buf[:i]), "\r"), offset + int64(i) + 1, nil
+}
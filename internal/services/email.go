@@ -0,0 +1,53 @@
+package services
+
+import "strings"
+
+// emailDomainAliases are domains that deliver to the same mailboxes as another.
+var emailDomainAliases = map[string]string{
+	"googlemail.com": "gmail.com",
+	"hotmail.com":    "outlook.com",
+	"live.com":       "outlook.com",
+	"me.com":         "icloud.com",
+	"mac.com":        "icloud.com",
+	"pm.me":          "proton.me",
+	"protonmail.com": "proton.me",
+}
+
+// plusTagDomains ignore anything after a '+' in the local part.
+var plusTagDomains = map[string]bool{
+	"gmail.com":    true,
+	"outlook.com":  true,
+	"icloud.com":   true,
+	"proton.me":    true,
+	"fastmail.com": true,
+}
+
+// CanonicalEmail returns the form of email used to tell whether two addresses
+// belong to the same person. It is always lowercased. With stripTags, plus
+// tags are dropped for providers known to ignore them, as are the dots Gmail
+// ignores, so bob+news@gmail.com and b.o.b@googlemail.com both give
+// bob@gmail.com.
+func CanonicalEmail(email string, stripTags bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !stripTags {
+		return email
+	}
+
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if alias, ok := emailDomainAliases[domain]; ok {
+		domain = alias
+	}
+	if plusTagDomains[domain] {
+		if plus := strings.IndexByte(local, '+'); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
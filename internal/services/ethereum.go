@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrInvalidAddress   = errors.New("invalid ethereum address")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// RecoverPersonalSignAddress returns the checksummed address that produced an
+// EIP-191 personal_sign signature over message.
+func RecoverPersonalSignAddress(message, signatureHex string) (string, error) {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil || len(sig) != 65 {
+		return "", ErrInvalidSignature
+	}
+
+	// Wallets send v as 27/28; some libraries use 0/1.
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return "", ErrInvalidSignature
+	}
+
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], sig[:64])
+
+	pubKey, _, err := ecdsa.RecoverCompact(compact, personalSignHash(message))
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	uncompressed := pubKey.SerializeUncompressed()
+	return ChecksumAddress(hex.EncodeToString(keccak256(uncompressed[1:])[12:]))
+}
+
+// ChecksumAddress validates a hex address and returns its EIP-55 form.
+func ChecksumAddress(address string) (string, error) {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if len(lower) != 40 {
+		return "", ErrInvalidAddress
+	}
+	if _, err := hex.DecodeString(lower); err != nil {
+		return "", ErrInvalidAddress
+	}
+
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+	var b strings.Builder
+	b.WriteString("0x")
+	for i, c := range lower {
+		if c >= 'a' && hash[i] >= '8' {
+			b.WriteRune(c - 'a' + 'A')
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// IsChecksumAddress reports whether address is written in valid EIP-55 form.
+func IsChecksumAddress(address string) bool {
+	checksummed, err := ChecksumAddress(address)
+	return err == nil && checksummed == address
+}
+
+func personalSignHash(message string) []byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))
+	return keccak256([]byte(prefix + message))
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// WalletLinkMessage is the text a user signs to prove they control address.
+func WalletLinkMessage(domain, address string, userID int64, nonce string, issuedAt time.Time) string {
+	return fmt.Sprintf(
+		"%s wants you to link your Ethereum account to ChainHub:\n%s\n\nLink this wallet to ChainHub account %d.\n\nNonce: %s\nIssued At: %s",
+		domain,
+		address,
+		userID,
+		nonce,
+		issuedAt.UTC().Format(time.RFC3339),
+	)
+}
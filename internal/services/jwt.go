@@ -1,17 +1,112 @@
 package services
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(secret string, userID int64) (string, error) {
+// AccessTokenType is the "typ" header of access tokens (RFC 9068), which no
+// other token carries.
+const AccessTokenType = "at+jwt"
+
+// GenerateJWT signs an access token. role is trusted until the token expires,
+// so changing a user's role should revoke their sessions.
+func GenerateJWT(keys *KeySet, userID, sessionID int64, role string, ttl time.Duration) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"iss":  keys.Issuer,
+		"aud":  keys.Audience,
+		"sub":  userID,
+		"sid":  sessionID,
+		"role": role,
+		"jti":  jti,
+		"iat":  now.Unix(),
+		"exp":  now.Add(ttl).Unix(),
+	}
+
+	return keys.sign(claims, AccessTokenType)
+}
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// ParseAccessToken verifies a token produced by GenerateJWT: its signature,
+// expiry, type, issuer and audience. Tokens for any other purpose fail.
+func (ks *KeySet) ParseAccessToken(tokenStr string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, ks.Keyfunc,
+		jwt.WithIssuer(ks.Issuer),
+		jwt.WithAudience(ks.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if typ, _ := token.Header["typ"].(string); typ != AccessTokenType {
+		return nil, ErrInvalidToken
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if _, ok := claims["pur"]; ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAPending        = "mfa_pending"
+	PurposeMagicLink         = "magic_link"
+)
+
+// GeneratePurposeToken signs a short-lived token for a single flow such as
+// email verification. Its audience is the flow, never the access token
+// audience, so services that verify access tokens against the published keys
+// reject it; the "pur" claim keeps it from being replayed in a different flow.
+func GeneratePurposeToken(keys *KeySet, purpose string, userID int64, extra jwt.MapClaims, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["iss"] = keys.Issuer
+	claims["aud"] = purposeAudience(keys, purpose)
+	claims["sub"] = userID
+	claims["pur"] = purpose
+	claims["exp"] = time.Now().Add(ttl).Unix()
+
+	return keys.Sign(claims)
+}
+
+func purposeAudience(keys *KeySet, purpose string) string {
+	return keys.Audience + ":" + purpose
+}
+
+// ParsePurposeToken verifies a token produced by GeneratePurposeToken for purpose.
+func ParsePurposeToken(keys *KeySet, tokenStr, purpose string) (int64, jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return 0, nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["pur"] != purpose {
+		return 0, nil, ErrInvalidToken
+	}
+	if aud, err := claims.GetAudience(); err != nil || len(aud) != 1 || aud[0] != purposeAudience(keys, purpose) {
+		return 0, nil, ErrInvalidToken
+	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, nil, ErrInvalidToken
+	}
+	return int64(sub), claims, nil
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeySet(t *testing.T, issuer, audience string) *KeySet {
+	t.Helper()
+
+	keys, err := LoadKeySet("HS256", "test-secret-test-secret-test-secret", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Issuer = issuer
+	keys.Audience = audience
+	return keys
+}
+
+func TestParseAccessToken(t *testing.T) {
+	keys := newTestKeySet(t, "chainhub", "chainhub-api")
+
+	token, err := GenerateJWT(keys, 7, 3, "user", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := keys.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken: %v", err)
+	}
+	if sub, _ := claims["sub"].(float64); sub != 7 {
+		t.Fatalf("sub = %v, want 7", claims["sub"])
+	}
+
+	other := newTestKeySet(t, "chainhub", "another-api")
+	if _, err := other.ParseAccessToken(token); err != ErrInvalidToken {
+		t.Fatalf("token for another audience: %v, want ErrInvalidToken", err)
+	}
+	other = newTestKeySet(t, "someone-else", "chainhub-api")
+	if _, err := other.ParseAccessToken(token); err != ErrInvalidToken {
+		t.Fatalf("token from another issuer: %v, want ErrInvalidToken", err)
+	}
+}
+
+// Purpose tokens are signed with the same keys but must never pass as access
+// tokens, and access tokens must never pass as purpose tokens.
+func TestPurposeTokensAreNotAccessTokens(t *testing.T) {
+	keys := newTestKeySet(t, "chainhub", "chainhub-api")
+
+	for _, purpose := range []string{PurposeEmailVerification, PurposeMFAPending, PurposeMagicLink} {
+		token, err := GeneratePurposeToken(keys, purpose, 7, nil, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := keys.ParseAccessToken(token); err != ErrInvalidToken {
+			t.Errorf("%s token as access token: %v, want ErrInvalidToken", purpose, err)
+		}
+		if _, _, err := ParsePurposeToken(keys, token, purpose); err != nil {
+			t.Errorf("%s token: %v", purpose, err)
+		}
+		if _, _, err := ParsePurposeToken(keys, token, PurposeMagicLink+"x"); err != ErrInvalidToken {
+			t.Errorf("%s token for another purpose: %v, want ErrInvalidToken", purpose, err)
+		}
+	}
+
+	access, err := GenerateJWT(keys, 7, 3, "user", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParsePurposeToken(keys, access, PurposeMagicLink); err != ErrInvalidToken {
+		t.Fatalf("access token as purpose token: %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestParsePurposeTokenRequiresAudience(t *testing.T) {
+	keys := newTestKeySet(t, "chainhub", "chainhub-api")
+
+	token, err := keys.Sign(jwt.MapClaims{
+		"sub": 7,
+		"pur": PurposeMagicLink,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParsePurposeToken(keys, token, PurposeMagicLink); err != ErrInvalidToken {
+		t.Fatalf("token without aud: %v, want ErrInvalidToken", err)
+	}
+}
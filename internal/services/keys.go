@@ -0,0 +1,320 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// KeySet signs access tokens with one key and verifies them against every
+// key that is still trusted. Rotating keys means promoting a new signing key
+// while keeping the previous public key in the verification set until all
+// tokens it signed have expired.
+type KeySet struct {
+	// Issuer and Audience go in every access token as "iss" and "aud".
+	// Verifiers must check both, along with the "at+jwt" type, since
+	// single-purpose tokens are signed with the same keys.
+	Issuer   string
+	Audience string
+
+	secret    []byte
+	signing   *signingKey
+	verifiers map[string]verificationKey
+}
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// JWK is a public key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet builds a KeySet. With HS256 the shared secret signs tokens;
+// otherwise signingKeyFile must hold a PKCS#8 or SEC1 private key for the
+// algorithm. verificationKeyFiles hold additional public (or private) keys
+// that are still accepted. A non-empty secret keeps HS256 tokens verifiable
+// so a deployment can move off the shared secret without logging users out.
+func LoadKeySet(algorithm, secret, signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	ks := &KeySet{
+		secret:    []byte(secret),
+		verifiers: make(map[string]verificationKey),
+	}
+
+	switch algorithm {
+	case AlgHS256:
+		if secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		return ks, nil
+	case AlgES256, AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+
+	if signingKeyFile == "" {
+		return nil, fmt.Errorf("%s requires a signing key file", algorithm)
+	}
+
+	private, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := newVerificationKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	if signer.method.Alg() != algorithm {
+		return nil, fmt.Errorf("signing key is %s, expected %s", signer.method.Alg(), algorithm)
+	}
+	ks.signing = &signingKey{kid: signer.kid, method: signer.method, private: private}
+	ks.verifiers[signer.kid] = signer
+
+	for _, path := range verificationKeyFiles {
+		public, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := newVerificationKey(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		ks.verifiers[key.kid] = key
+	}
+
+	return ks, nil
+}
+
+// Sign returns a compact JWT for claims using the current signing key.
+func (ks *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	return ks.sign(claims, "")
+}
+
+// sign is Sign with the "typ" header set to typ, if given.
+func (ks *KeySet) sign(claims jwt.MapClaims, typ string) (string, error) {
+	method := jwt.SigningMethod(jwt.SigningMethodHS256)
+	if ks.signing != nil {
+		method = ks.signing.method
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	if ks.signing == nil {
+		return token.SignedString(ks.secret)
+	}
+	token.Header["kid"] = ks.signing.kid
+	return token.SignedString(ks.signing.private)
+}
+
+// Keyfunc resolves the verification key for a parsed token.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(ks.secret) == 0 || t.Method.Alg() != AlgHS256 {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := ks.verifiers[kid]
+	if !ok || key.method.Alg() != t.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return key.public, nil
+}
+
+// JWKS lists every public verification key.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.verifiers))}
+	for _, key := range ks.verifiers {
+		jwk, err := publicJWK(key.public)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = key.kid
+		jwk.Alg = key.method.Alg()
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func newVerificationKey(public crypto.PublicKey) (verificationKey, error) {
+	var method jwt.SigningMethod
+	switch k := public.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return verificationKey{}, errors.New("only P-256 ECDSA keys are supported")
+		}
+		method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	kid, err := thumbprint(public)
+	if err != nil {
+		return verificationKey{}, err
+	}
+	return verificationKey{kid: kid, method: method, public: public}, nil
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint, used as the key ID.
+func thumbprint(public crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return "", err
+	}
+
+	// Members must be in lexicographic order with no whitespace.
+	var canonical []byte
+	if jwk.Y != "" {
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y})
+	} else {
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func publicJWK(public crypto.PublicKey) (JWK, error) {
+	switch k := public.(type) {
+	case *ecdsa.PublicKey:
+		ecdhKey, err := k.ECDH()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Uncompressed point: 0x04 || X || Y, each 32 bytes for P-256.
+		point := ecdhKey.Bytes()
+		return JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(point[1:33]),
+			Y:   base64.RawURLEncoding.EncodeToString(point[33:65]),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", public)
+	}
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key", path)
+		}
+		return signer, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+	}
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != "PUBLIC KEY" {
+		// Accept private keys too, so the previous signing key file can be
+		// kept as-is during rotation.
+		private, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return private.Public(), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return block, nil
+}
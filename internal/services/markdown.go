@@ -0,0 +1,126 @@
+package services
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// RenderBio renders the limited Markdown allowed in tree bios as HTML. It
+// understands **bold**, *italic* or _italic_, `code`, [links](https://...)
+// and paragraphs; line breaks are kept. Everything else, HTML included, is
+// escaped and shown as typed, so the output is safe to insert into a page.
+// Links must be http, https or mailto; others are rendered as plain text.
+func RenderBio(bio string) string {
+	if bio == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, para := range strings.Split(bio, "\n\n") {
+		b.WriteString("<p>")
+		for i, line := range strings.Split(para, "\n") {
+			if i > 0 {
+				b.WriteString("<br>")
+			}
+			renderInline(&b, line, true)
+		}
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+// renderInline writes one line of Markdown. Links may not be nested, so the
+// text inside a link is rendered with allowLinks false.
+func renderInline(b *strings.Builder, s string, allowLinks bool) {
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "**"):
+			if end := strings.Index(s[2:], "**"); end > 0 && isEmphasized(s[2:2+end]) {
+				b.WriteString("<strong>")
+				renderInline(b, s[2:2+end], allowLinks)
+				b.WriteString("</strong>")
+				s = s[2+end+2:]
+				continue
+			}
+		case s[0] == '*' || s[0] == '_':
+			if end := strings.IndexByte(s[1:], s[0]); end > 0 && isEmphasized(s[1:1+end]) {
+				b.WriteString("<em>")
+				renderInline(b, s[1:1+end], allowLinks)
+				b.WriteString("</em>")
+				s = s[1+end+1:]
+				continue
+			}
+		case s[0] == '`':
+			if end := strings.IndexByte(s[1:], '`'); end > 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(s[1 : 1+end]))
+				b.WriteString("</code>")
+				s = s[1+end+1:]
+				continue
+			}
+		case s[0] == '[' && allowLinks:
+			if text, href, rest, ok := parseMarkdownLink(s); ok {
+				if safe, ok := safeLinkURL(href); ok {
+					b.WriteString(`<a href="`)
+					b.WriteString(html.EscapeString(safe))
+					b.WriteString(`" rel="nofollow noopener noreferrer ugc" target="_blank">`)
+					renderInline(b, text, false)
+					b.WriteString("</a>")
+				} else {
+					renderInline(b, text, false)
+				}
+				s = rest
+				continue
+			}
+		}
+
+		// Copy plain text up to the next character that may start markup.
+		n := strings.IndexAny(s[1:], "*_`[")
+		if n < 0 {
+			n = len(s) - 1
+		}
+		b.WriteString(html.EscapeString(s[:1+n]))
+		s = s[1+n:]
+	}
+}
+
+// isEmphasized reports whether text between emphasis markers may be
+// emphasized: as in Markdown, "a * b * c" is left alone.
+func isEmphasized(text string) bool {
+	return !strings.HasPrefix(text, " ") && !strings.HasSuffix(text, " ")
+}
+
+// parseMarkdownLink parses [text](href) at the start of s.
+func parseMarkdownLink(s string) (text, href, rest string, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 2 {
+		return "", "", "", false
+	}
+	closeHref := strings.IndexByte(s[closeText+2:], ')')
+	if closeHref < 1 {
+		return "", "", "", false
+	}
+	text = s[1:closeText]
+	href = strings.TrimSpace(s[closeText+2 : closeText+2+closeHref])
+	return text, href, s[closeText+2+closeHref+1:], true
+}
+
+func safeLinkURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
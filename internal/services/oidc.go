@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// OIDCClient runs the authorization code flow (with PKCE) against one
+// OpenID Connect provider. Discovery happens on first use so a provider that
+// is briefly unreachable does not keep the API from starting.
+type OIDCClient struct {
+	Name string
+
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// OIDCIdentity is what ChainHub keeps from a verified ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewOIDCClient(name, issuer, clientID, clientSecret, redirectURL string, scopes []string) *OIDCClient {
+	return &OIDCClient{
+		Name:         name,
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       scopes,
+	}
+}
+
+// AuthCodeURL returns the provider URL to send the user to. The verifier is
+// kept server-side; only its S256 challenge goes to the provider.
+func (c *OIDCClient) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	cfg, _, err := c.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code and verifies the returned ID token,
+// including its nonce.
+func (c *OIDCClient) Exchange(ctx context.Context, code, verifier, nonce string) (OIDCIdentity, error) {
+	cfg, provider, err := c.oauth2Config(ctx)
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OIDCIdentity{}, fmt.Errorf("exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: c.clientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+	if idToken.Nonce != nonce {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	var claims struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return OIDCIdentity{}, ErrInvalidIDToken
+	}
+
+	// Some providers send email_verified as the string "true".
+	verified := claims.EmailVerified == true || claims.EmailVerified == "true"
+
+	return OIDCIdentity{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: verified && claims.Email != "",
+		Name:          claims.Name,
+	}, nil
+}
+
+// GeneratePKCEVerifier returns a random RFC 7636 code verifier.
+func GeneratePKCEVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+func (c *OIDCClient) oauth2Config(ctx context.Context) (oauth2.Config, *oidc.Provider, error) {
+	provider, err := c.discover(ctx)
+	if err != nil {
+		return oauth2.Config{}, nil, err
+	}
+	return oauth2.Config{
+		ClientID:     c.clientID,
+		ClientSecret: c.clientSecret,
+		RedirectURL:  c.redirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       c.scopes,
+	}, provider, nil
+}
+
+func (c *OIDCClient) discover(ctx context.Context) (*oidc.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, c.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover %s: %w", c.Name, err)
+	}
+	c.provider = provider
+	return provider, nil
+}
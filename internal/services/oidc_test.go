@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chainhub-api/internal/testutil"
+)
+
+func newTestOIDC(t *testing.T) (*testutil.OIDCProvider, *OIDCClient) {
+	t.Helper()
+
+	provider, err := testutil.NewOIDCProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+	client := NewOIDCClient("test", provider.Issuer(), provider.ClientID, provider.ClientSecret,
+		"http://localhost:3000/auth/oidc/callback", []string{"openid", "email", "profile"})
+	return provider, client
+}
+
+// authorize starts a login and has the provider approve it as user. It
+// returns the code for the callback.
+func authorize(t *testing.T, provider *testutil.OIDCProvider, client *OIDCClient, state, nonce, verifier string, user testutil.OIDCUser) string {
+	t.Helper()
+
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, gotState, err := provider.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if gotState != state {
+		t.Fatalf("provider returned state %q, want %q", gotState, state)
+	}
+	return code
+}
+
+func TestOIDCExchange(t *testing.T) {
+	provider, client := newTestOIDC(t)
+	verifier := GeneratePKCEVerifier()
+	code := authorize(t, provider, client, "state-1", "nonce-1", verifier, testutil.OIDCUser{
+		Subject:       "alice-sub",
+		Email:         "alice@example.com",
+		EmailVerified: true,
+		Name:          "Alice",
+	})
+
+	identity, err := client.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := OIDCIdentity{Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	if identity != want {
+		t.Fatalf("identity = %+v, want %+v", identity, want)
+	}
+}
+
+func TestOIDCExchangeRejectsWrongPKCEVerifier(t *testing.T) {
+	provider, client := newTestOIDC(t)
+	code := authorize(t, provider, client, "state-1", "nonce-1", GeneratePKCEVerifier(), testutil.OIDCUser{Subject: "alice-sub"})
+
+	if _, err := client.Exchange(context.Background(), code, GeneratePKCEVerifier(), "nonce-1"); err == nil {
+		t.Fatal("Exchange accepted a code with another PKCE verifier")
+	}
+}
+
+func TestOIDCExchangeRejectsNonceMismatch(t *testing.T) {
+	provider, client := newTestOIDC(t)
+
+	t.Run("expected nonce differs", func(t *testing.T) {
+		verifier := GeneratePKCEVerifier()
+		code := authorize(t, provider, client, "state-1", "nonce-1", verifier, testutil.OIDCUser{Subject: "alice-sub"})
+		if _, err := client.Exchange(context.Background(), code, verifier, "nonce-2"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("Exchange = %v, want ErrInvalidIDToken", err)
+		}
+	})
+
+	t.Run("token carries another nonce", func(t *testing.T) {
+		verifier := GeneratePKCEVerifier()
+		code := authorize(t, provider, client, "state-1", "nonce-1", verifier, testutil.OIDCUser{Subject: "alice-sub", Nonce: "replayed"})
+		if _, err := client.Exchange(context.Background(), code, verifier, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("Exchange = %v, want ErrInvalidIDToken", err)
+		}
+	})
+}
+
+func TestOIDCExchangeEmailVerified(t *testing.T) {
+	provider, client := newTestOIDC(t)
+
+	for _, tc := range []struct {
+		name     string
+		claim    interface{}
+		email    string
+		verified bool
+	}{
+		{"true", true, "alice@example.com", true},
+		{"string true", "true", "alice@example.com", true},
+		{"false", false, "alice@example.com", false},
+		{"string false", "false", "alice@example.com", false},
+		{"missing", nil, "alice@example.com", false},
+		{"no email", true, "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := GeneratePKCEVerifier()
+			code := authorize(t, provider, client, "state", "nonce", verifier, testutil.OIDCUser{
+				Subject:       "alice-sub",
+				Email:         tc.email,
+				EmailVerified: tc.claim,
+			})
+			identity, err := client.Exchange(context.Background(), code, verifier, "nonce")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.EmailVerified != tc.verified {
+				t.Fatalf("EmailVerified = %t, want %t", identity.EmailVerified, tc.verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// Argon2Params are the Argon2id work factors. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordHasher hashes new passwords with the preferred algorithm and
+// verifies hashes made with any supported one. Hashes carry their algorithm
+// and parameters (bcrypt's $2a$COST$ prefix, Argon2id's PHC string), so
+// raising the work factors only affects hashes made afterwards, and
+// Verify tells callers which stored hashes are due for an upgrade.
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+func NewPasswordHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*PasswordHasher, error) {
+	if algorithm != PasswordHashBcrypt && algorithm != PasswordHashArgon2id {
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 ||
+		argon2Params.SaltLength == 0 || argon2Params.KeyLength == 0 {
+		return nil, errors.New("argon2 parameters must be positive")
+	}
+	return &PasswordHasher{algorithm: algorithm, bcryptCost: bcryptCost, argon2: argon2Params}, nil
+}
+
+// Hash hashes password with the preferred algorithm and parameters.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordHashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, h.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.Memory, h.argon2.Parallelism, h.argon2.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.argon2.Memory,
+		h.argon2.Iterations,
+		h.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches hash and, if it does, whether hash
+// should be replaced by Hash(password) because it uses another algorithm or
+// other parameters than the preferred ones.
+func (h *PasswordHasher) Verify(hash, password string) (ok, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false, nil
+		}
+		current := h.algorithm == PasswordHashArgon2id &&
+			params.Memory == h.argon2.Memory &&
+			params.Iterations == h.argon2.Iterations &&
+			params.Parallelism == h.argon2.Parallelism &&
+			uint32(len(salt)) == h.argon2.SaltLength &&
+			uint32(len(key)) == h.argon2.KeyLength
+		return true, !current, nil
+
+	case strings.HasPrefix(hash, "$2"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.algorithm != PasswordHashBcrypt || cost != h.bcryptCost, nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+// decodeArgon2id parses $argon2id$v=19$m=MEMORY,t=ITERATIONS,p=PARALLELISM$SALT$KEY.
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrUnknownPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
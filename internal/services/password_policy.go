@@ -0,0 +1,480 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PasswordMinLength = 8
+	PasswordMaxScore  = 4
+
+	passwordEstimateMaxLength = 100
+	passwordWordMaxLength     = 64
+)
+
+// Password problem codes, returned to clients as-is.
+const (
+	PasswordTooShort = "too_short"
+	PasswordTooWeak  = "too_weak"
+	PasswordBreached = "breached"
+)
+
+type PasswordProblem struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicy decides whether a new password is acceptable: long enough,
+// not too easy to guess and, if a corpus is configured, not known from a
+// breach.
+type PasswordPolicy struct {
+	minScore int
+	breached *BreachedPasswords
+}
+
+// NewPasswordPolicy opens the breached password corpus at breachedFile, if
+// any. minScore is the lowest EstimatePasswordStrength score accepted.
+func NewPasswordPolicy(minScore int, breachedFile string) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{minScore: minScore}
+	if breachedFile != "" {
+		breached, err := OpenBreachedPasswords(breachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("open breached password corpus: %w", err)
+		}
+		p.breached = breached
+	}
+	return p, nil
+}
+
+// Check returns every problem with password, or nil if it is acceptable.
+// userInputs are the account's email, username and the like, which make a
+// password easy to guess for anyone who knows the account.
+func (p *PasswordPolicy) Check(password string, userInputs ...string) []PasswordProblem {
+	var problems []PasswordProblem
+	if utf8.RuneCountInString(password) < PasswordMinLength {
+		problems = append(problems, PasswordProblem{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters", PasswordMinLength),
+		})
+	}
+
+	if strength := EstimatePasswordStrength(password, userInputs...); strength.Score < p.minScore {
+		problems = append(problems, PasswordProblem{
+			Code:    PasswordTooWeak,
+			Message: "password is too easy to guess: " + strength.Warning,
+		})
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			// A broken corpus should not stop everyone from setting a password.
+			log.Printf("password policy: breached password lookup failed: %v", err)
+		} else if breached {
+			problems = append(problems, PasswordProblem{
+				Code:    PasswordBreached,
+				Message: "password has appeared in a data breach; choose a different one",
+			})
+		}
+	}
+	return problems
+}
+
+// PasswordStrength scores a password from 0 (guessable in under a thousand
+// tries) to 4 (over ten billion), like zxcvbn.
+type PasswordStrength struct {
+	Score   int
+	Guesses float64
+	Warning string
+}
+
+//go:embed password_words.txt
+var passwordWordList string
+
+// passwordWords ranks common passwords and words by how early a guesser
+// would try them.
+var passwordWords = func() map[string]int {
+	words := make(map[string]int)
+	for i, word := range strings.Fields(passwordWordList) {
+		if _, ok := words[word]; !ok {
+			words[word] = i + 1
+		}
+	}
+	return words
+}()
+
+const (
+	patternUserInput  = "user_input"
+	patternDictionary = "dictionary"
+	patternKeyboard   = "keyboard"
+	patternSequence   = "sequence"
+	patternRepeat     = "repeat"
+	patternYear       = "year"
+)
+
+// patternWarnings are listed most telling first; a weak password is described
+// by the first pattern it contains.
+var patternWarnings = []struct {
+	pattern string
+	warning string
+}{
+	{patternUserInput, "it contains your email or username"},
+	{patternDictionary, "it is based on a common word or password"},
+	{patternKeyboard, "it contains a keyboard pattern like qwerty"},
+	{patternSequence, "it contains a sequence like abc or 123"},
+	{patternRepeat, "it repeats characters"},
+	{patternYear, "it contains a year"},
+}
+
+type passwordMatch struct {
+	start, end int // runes [start, end)
+	bits       float64
+	pattern    string
+}
+
+// EstimatePasswordStrength estimates how many guesses an attacker who tries
+// common passwords, words, the user's own details, keyboard walks, sequences,
+// repeats and years before brute force would need. It follows zxcvbn: the
+// password is split into the cheapest sequence of such patterns and
+// brute-forced characters.
+func EstimatePasswordStrength(password string, userInputs ...string) PasswordStrength {
+	runes := []rune(password)
+	// Matching is quadratic; anything past this only makes a password stronger.
+	if len(runes) > passwordEstimateMaxLength {
+		runes = runes[:passwordEstimateMaxLength]
+	}
+	bits, patterns := minimumGuessBits(runes, passwordInputs(userInputs))
+
+	strength := PasswordStrength{Guesses: math.Exp2(bits)}
+	switch {
+	case bits < math.Log2(1e3):
+		strength.Score = 0
+	case bits < math.Log2(1e6):
+		strength.Score = 1
+	case bits < math.Log2(1e8):
+		strength.Score = 2
+	case bits < math.Log2(1e10):
+		strength.Score = 3
+	default:
+		strength.Score = 4
+	}
+
+	strength.Warning = "add more words or characters"
+	for _, w := range patternWarnings {
+		if patterns[w.pattern] {
+			strength.Warning = w.warning
+			break
+		}
+	}
+	return strength
+}
+
+// passwordInputs splits user details into the words a guesser would try, e.g.
+// an email gives the address, its local part and that part's pieces.
+func passwordInputs(userInputs []string) map[string]int {
+	inputs := make(map[string]int)
+	add := func(s string) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if utf8.RuneCountInString(s) >= 3 {
+			if _, ok := inputs[s]; !ok {
+				inputs[s] = len(inputs) + 1
+			}
+		}
+	}
+	for _, input := range userInputs {
+		add(input)
+		local, _, _ := strings.Cut(input, "@")
+		add(local)
+		for _, part := range strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(part)
+		}
+	}
+	return inputs
+}
+
+func minimumGuessBits(runes []rune, inputs map[string]int) (float64, map[string]bool) {
+	n := len(runes)
+	if n == 0 {
+		return 0, nil
+	}
+
+	matches := findPasswordMatches(runes, inputs)
+	bruteforce := math.Log2(float64(bruteforceCardinality(runes)))
+
+	// best[k] is the cheapest way to guess the first k runes, reached via
+	// from[k]: a match, or nil for one brute-forced rune.
+	best := make([]float64, n+1)
+	from := make([]*passwordMatch, n+1)
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] + bruteforce
+		from[k] = nil
+		for i := range matches {
+			m := &matches[i]
+			if m.end != k {
+				continue
+			}
+			// Each pattern costs a bit more, for the guesser having to
+			// combine it with the others.
+			if bits := best[m.start] + m.bits + 1; bits < best[k] {
+				best[k] = bits
+				from[k] = m
+			}
+		}
+	}
+
+	patterns := make(map[string]bool)
+	for k := n; k > 0; {
+		if m := from[k]; m != nil {
+			patterns[m.pattern] = true
+			k = m.start
+		} else {
+			k--
+		}
+	}
+	return best[n], patterns
+}
+
+func findPasswordMatches(runes []rune, inputs map[string]int) []passwordMatch {
+	var matches []passwordMatch
+	matches = append(matches, dictionaryMatches(runes, inputs)...)
+	matches = append(matches, keyboardMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, inputs)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+// passwordLeet undoes common character substitutions, as in p@ssw0rd.
+var passwordLeet = map[rune]rune{
+	'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o',
+	'$': 's', '5': 's', '7': 't', '+': 't',
+}
+
+func dictionaryMatches(runes []rune, inputs map[string]int) []passwordMatch {
+	lower := make([]rune, len(runes))
+	unleet := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+		unleet[i] = lower[i]
+		if plain, ok := passwordLeet[lower[i]]; ok {
+			unleet[i] = plain
+		}
+	}
+
+	var matches []passwordMatch
+	for i := range runes {
+		for j := i + 3; j <= len(runes) && j-i <= passwordWordMaxLength; j++ {
+			leet := false
+			word := string(lower[i:j])
+			rank, pattern := lookupPasswordWord(word, inputs)
+			if rank == 0 {
+				word = string(unleet[i:j])
+				rank, pattern = lookupPasswordWord(word, inputs)
+				leet = true
+			}
+			if rank == 0 {
+				continue
+			}
+
+			bits := math.Log2(float64(rank)) + uppercaseBits(runes[i:j])
+			if leet {
+				bits++
+			}
+			matches = append(matches, passwordMatch{start: i, end: j, bits: bits, pattern: pattern})
+		}
+	}
+	return matches
+}
+
+func lookupPasswordWord(word string, inputs map[string]int) (int, string) {
+	if rank, ok := inputs[word]; ok {
+		return rank, patternUserInput
+	}
+	if rank, ok := passwordWords[word]; ok {
+		return rank, patternDictionary
+	}
+	return 0, ""
+}
+
+// uppercaseBits is the cost of guessing which letters of a word are
+// capitalized; a capital first letter or all capitals are tried first.
+func uppercaseBits(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0 || (upper == 1 && unicode.IsUpper(word[0])):
+		return 1
+	default:
+		return float64(upper + lower)
+	}
+}
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// keyboardMatches finds runs of at least four neighbouring keys on a row, in
+// either direction.
+func keyboardMatches(runes []rune) []passwordMatch {
+	position := func(r rune) (int, int) {
+		r = unicode.ToLower(r)
+		for row, keys := range keyboardRows {
+			if col := strings.IndexRune(keys, r); col >= 0 {
+				return row, col
+			}
+		}
+		return -1, -1
+	}
+
+	var matches []passwordMatch
+	for i := 0; i < len(runes); {
+		j := i + 1
+		row, col := position(runes[i])
+		dir := 0
+		for row >= 0 && j < len(runes) {
+			nextRow, nextCol := position(runes[j])
+			step := nextCol - col
+			if nextRow != row || (step != 1 && step != -1) || (dir != 0 && step != dir) {
+				break
+			}
+			dir, col = step, nextCol
+			j++
+		}
+		if length := j - i; length >= 4 {
+			bits := math.Log2(float64(len(keyboardRows)*10*length)) + uppercaseBits(runes[i:j])
+			matches = append(matches, passwordMatch{start: i, end: j, bits: bits, pattern: patternKeyboard})
+		}
+		i = j
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of at least three letters or digits that go up or
+// down one at a time, like abc or 987.
+func sequenceMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+	for i := 0; i < len(runes); {
+		j := i + 1
+		delta := rune(0)
+		for j < len(runes) && sameCharClass(runes[j-1], runes[j]) {
+			d := runes[j] - runes[j-1]
+			if (d != 1 && d != -1) || (delta != 0 && d != delta) {
+				break
+			}
+			delta = d
+			j++
+		}
+		if length := j - i; length >= 3 {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("aAzZ019", runes[i]):
+				base = 4
+			case unicode.IsDigit(runes[i]):
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, passwordMatch{start: i, end: j, bits: math.Log2(base * float64(length)), pattern: patternSequence})
+		}
+		i = j
+	}
+	return matches
+}
+
+func sameCharClass(a, b rune) bool {
+	switch {
+	case unicode.IsDigit(a):
+		return unicode.IsDigit(b)
+	case unicode.IsLower(a):
+		return unicode.IsLower(b)
+	case unicode.IsUpper(a):
+		return unicode.IsUpper(b)
+	}
+	return false
+}
+
+// repeatMatches finds a unit repeated back to back, like aaa or abcabc. The
+// cost is that of guessing the unit once, plus how many times it repeats.
+func repeatMatches(runes []rune, inputs map[string]int) []passwordMatch {
+	var matches []passwordMatch
+	for i := range runes {
+		for unit := 1; i+2*unit <= len(runes); unit++ {
+			count := 1
+			for end := i + (count+1)*unit; end <= len(runes) && string(runes[end-unit:end]) == string(runes[i:i+unit]); end += unit {
+				count++
+			}
+			if count < 2 || count*unit < 3 {
+				continue
+			}
+			unitBits, _ := minimumGuessBits(runes[i:i+unit], inputs)
+			matches = append(matches, passwordMatch{
+				start:   i,
+				end:     i + count*unit,
+				bits:    unitBits + math.Log2(float64(count)),
+				pattern: patternRepeat,
+			})
+		}
+	}
+	return matches
+}
+
+// yearMatches finds four-digit years from 1900 to 2099.
+func yearMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+	for i := 0; i+4 <= len(runes); i++ {
+		year := string(runes[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) &&
+			unicode.IsDigit(runes[i+2]) && unicode.IsDigit(runes[i+3]) {
+			matches = append(matches, passwordMatch{start: i, end: i + 4, bits: math.Log2(200), pattern: patternYear})
+		}
+	}
+	return matches
+}
+
+// bruteforceCardinality is the size of the smallest character set, out of
+// lowercase, uppercase, digits, symbols and everything else, covering the
+// password.
+func bruteforceCardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	cardinality := 0
+	for _, set := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if set.present {
+			cardinality += set.size
+		}
+	}
+	return cardinality
+}
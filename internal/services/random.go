@@ -0,0 +1,19 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// GenerateNonce returns a random alphanumeric nonce suitable for EIP-4361.
+func GenerateNonce() (string, error) {
+	return randomHex(16)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
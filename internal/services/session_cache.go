@@ -0,0 +1,106 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"chainhub-api/internal/repo"
+)
+
+const sessionCacheMaxEntries = 10000
+
+// A full cache frees 1/sessionCacheEvictFraction of its room at once, so it is
+// scanned once per that many inserts rather than on every one.
+const sessionCacheEvictFraction = 10
+
+// SessionCache answers "is this session still active?" for the auth
+// middleware. Results are kept for ttl so a busy client costs one query per
+// window instead of one per request; revocations on another replica take
+// effect once the cached entry expires.
+type SessionCache struct {
+	db         *sql.DB
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[int64]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+func NewSessionCache(db *sql.DB, ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		db:         db,
+		ttl:        ttl,
+		maxEntries: sessionCacheMaxEntries,
+		entries:    make(map[int64]sessionCacheEntry),
+	}
+}
+
+func (c *SessionCache) IsSessionActive(sessionID int64) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[sessionID]
+	c.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < c.ttl {
+		return entry.active, nil
+	}
+
+	active, err := repo.TouchSession(c.db, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	c.storeLocked(sessionID, sessionCacheEntry{active: active, checkedAt: now})
+	c.mu.Unlock()
+
+	return active, nil
+}
+
+// MarkRevoked makes a revocation visible on this replica immediately.
+func (c *SessionCache) MarkRevoked(sessionID int64) {
+	c.mu.Lock()
+	c.storeLocked(sessionID, sessionCacheEntry{active: false, checkedAt: time.Now()})
+	c.mu.Unlock()
+}
+
+// storeLocked adds entry, making room first if the cache is full: expired
+// entries go first, then the oldest until a tenth of the cache is free.
+// Evicting a revocation is safe; the next check reads it from the database.
+func (c *SessionCache) storeLocked(sessionID int64, entry sessionCacheEntry) {
+	if _, ok := c.entries[sessionID]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictExpiredLocked(entry.checkedAt)
+		lowWater := c.maxEntries - c.maxEntries/sessionCacheEvictFraction - 1
+		if len(c.entries) > lowWater {
+			c.evictOldestLocked(len(c.entries) - lowWater)
+		}
+	}
+	c.entries[sessionID] = entry
+}
+
+func (c *SessionCache) evictExpiredLocked(now time.Time) {
+	for id, entry := range c.entries {
+		if now.Sub(entry.checkedAt) >= c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
+
+func (c *SessionCache) evictOldestLocked(n int) {
+	ids := make([]int64, 0, len(c.entries))
+	for id := range c.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return c.entries[ids[i]].checkedAt.Before(c.entries[ids[j]].checkedAt)
+	})
+	for _, id := range ids[:min(n, len(ids))] {
+		delete(c.entries, id)
+	}
+}
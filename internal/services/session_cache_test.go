@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionCacheStaysWithinCap(t *testing.T) {
+	cache := NewSessionCache(nil, time.Hour)
+	cache.maxEntries = 100
+
+	// Every entry is still live, so only the oldest can make room.
+	for id := int64(1); id <= 1000; id++ {
+		cache.MarkRevoked(id)
+		if n := len(cache.entries); n > cache.maxEntries {
+			t.Fatalf("after %d inserts the cache holds %d entries, cap %d", id, n, cache.maxEntries)
+		}
+	}
+
+	// The newest entry is answered from the cache, without the database.
+	active, err := cache.IsSessionActive(1000)
+	if err != nil || active {
+		t.Fatalf("IsSessionActive(1000) = %t, %v; want false from the cache", active, err)
+	}
+	if _, ok := cache.entries[1]; ok {
+		t.Fatal("the oldest entry survived")
+	}
+}
+
+func TestSessionCacheEvictsExpiredFirst(t *testing.T) {
+	cache := NewSessionCache(nil, time.Minute)
+	cache.maxEntries = 10
+
+	stale := time.Now().Add(-time.Hour)
+	for id := int64(1); id <= 5; id++ {
+		cache.entries[id] = sessionCacheEntry{checkedAt: stale}
+	}
+	for id := int64(6); id <= 10; id++ {
+		cache.MarkRevoked(id)
+	}
+	cache.MarkRevoked(11)
+
+	for id := int64(6); id <= 11; id++ {
+		if _, ok := cache.entries[id]; !ok {
+			t.Errorf("live entry %d was evicted", id)
+		}
+	}
+	if len(cache.entries) != 6 {
+		t.Fatalf("cache holds %d entries, want the 6 live ones", len(cache.entries))
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+var ErrInvalidSIWEMessage = errors.New("invalid SIWE message")
+
+// SIWEMessage is a parsed EIP-4361 Sign-In With Ethereum message.
+type SIWEMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// SIWEExpectations are the server-side values a message must match.
+type SIWEExpectations struct {
+	Domain   string
+	ChainIDs []int64
+	Now      time.Time
+}
+
+func ParseSIWEMessage(raw string) (SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return SIWEMessage{}, ErrInvalidSIWEMessage
+	}
+
+	var msg SIWEMessage
+
+	header := lines[0]
+	if !strings.HasSuffix(header, siweHeaderSuffix) {
+		return SIWEMessage{}, siweError("missing header")
+	}
+	msg.Domain = strings.TrimSuffix(header, siweHeaderSuffix)
+	if i := strings.Index(msg.Domain, "://"); i >= 0 {
+		msg.Domain = msg.Domain[i+3:]
+	}
+	if msg.Domain == "" {
+		return SIWEMessage{}, siweError("missing domain")
+	}
+
+	msg.Address = lines[1]
+	if !IsChecksumAddress(msg.Address) {
+		return SIWEMessage{}, siweError("address must be EIP-55 checksummed")
+	}
+
+	if lines[2] != "" {
+		return SIWEMessage{}, siweError("expected blank line after address")
+	}
+
+	// The statement is optional; when present it is followed by a blank line.
+	i := 3
+	if lines[i] != "" && !strings.HasPrefix(lines[i], "URI: ") {
+		msg.Statement = lines[i]
+		i++
+	}
+	if i >= len(lines) || lines[i] != "" {
+		return SIWEMessage{}, siweError("expected blank line before fields")
+	}
+	i++
+
+	fields := lines[i:]
+	next := func(tag string, required bool) (string, error) {
+		if len(fields) > 0 && strings.HasPrefix(fields[0], tag+": ") {
+			value := strings.TrimPrefix(fields[0], tag+": ")
+			fields = fields[1:]
+			return value, nil
+		}
+		if required {
+			return "", siweError("missing " + tag)
+		}
+		return "", nil
+	}
+
+	var err error
+	if msg.URI, err = next("URI", true); err != nil {
+		return SIWEMessage{}, err
+	}
+	if msg.Version, err = next("Version", true); err != nil {
+		return SIWEMessage{}, err
+	}
+
+	chainID, err := next("Chain ID", true)
+	if err != nil {
+		return SIWEMessage{}, err
+	}
+	msg.ChainID, err = strconv.ParseInt(chainID, 10, 64)
+	if err != nil {
+		return SIWEMessage{}, siweError("invalid Chain ID")
+	}
+
+	if msg.Nonce, err = next("Nonce", true); err != nil {
+		return SIWEMessage{}, err
+	}
+	if len(msg.Nonce) < 8 || !isAlphanumeric(msg.Nonce) {
+		return SIWEMessage{}, siweError("invalid Nonce")
+	}
+
+	issuedAt, err := next("Issued At", true)
+	if err != nil {
+		return SIWEMessage{}, err
+	}
+	if msg.IssuedAt, err = time.Parse(time.RFC3339, issuedAt); err != nil {
+		return SIWEMessage{}, siweError("invalid Issued At")
+	}
+
+	if msg.ExpirationTime, err = optionalTime(next("Expiration Time", false)); err != nil {
+		return SIWEMessage{}, siweError("invalid Expiration Time")
+	}
+	if msg.NotBefore, err = optionalTime(next("Not Before", false)); err != nil {
+		return SIWEMessage{}, siweError("invalid Not Before")
+	}
+	if msg.RequestID, err = next("Request ID", false); err != nil {
+		return SIWEMessage{}, err
+	}
+
+	if len(fields) > 0 && fields[0] == "Resources:" {
+		for _, line := range fields[1:] {
+			if !strings.HasPrefix(line, "- ") {
+				break
+			}
+			msg.Resources = append(msg.Resources, strings.TrimPrefix(line, "- "))
+		}
+		fields = fields[1+len(msg.Resources):]
+	}
+
+	// Allow a single trailing newline, nothing else.
+	if len(fields) > 1 || (len(fields) == 1 && fields[0] != "") {
+		return SIWEMessage{}, siweError("unexpected content after fields")
+	}
+
+	return msg, nil
+}
+
+// Validate checks the message against what this server is willing to accept.
+func (m SIWEMessage) Validate(expect SIWEExpectations) error {
+	if m.Version != "1" {
+		return siweError("unsupported version")
+	}
+	if !strings.EqualFold(m.Domain, expect.Domain) {
+		return siweError("domain mismatch")
+	}
+
+	uri, err := url.Parse(m.URI)
+	if err != nil || uri.Scheme == "" || !strings.EqualFold(uri.Host, expect.Domain) {
+		return siweError("URI does not match domain")
+	}
+
+	chainAllowed := false
+	for _, id := range expect.ChainIDs {
+		if id == m.ChainID {
+			chainAllowed = true
+			break
+		}
+	}
+	if !chainAllowed {
+		return siweError("unsupported Chain ID")
+	}
+
+	// Tolerate small clock skew between the wallet and the server.
+	const skew = time.Minute
+	if m.IssuedAt.After(expect.Now.Add(skew)) {
+		return siweError("message issued in the future")
+	}
+	if m.ExpirationTime != nil && !expect.Now.Before(*m.ExpirationTime) {
+		return siweError("message expired")
+	}
+	if m.NotBefore != nil && expect.Now.Add(skew).Before(*m.NotBefore) {
+		return siweError("message not yet valid")
+	}
+	return nil
+}
+
+func optionalTime(value string, err error) (*time.Time, error) {
+	if err != nil || value == "" {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func siweError(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSIWEMessage, reason)
+}
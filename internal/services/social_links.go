@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	SocialLinksMax      = 12
+	SocialURLMaxLength  = 2048
+	SocialPlatformEmail = "email"
+	SocialPlatformWeb   = "website"
+)
+
+var (
+	ErrSocialPlatform = errors.New("unknown social platform")
+	ErrSocialURL      = errors.New("not a valid URL")
+)
+
+// SocialPlatform is a kind of social icon a tree can show. A URL belongs to
+// the platform when its host is one of Hosts (a "www." or "m." prefix is
+// ignored) and its path matches Path. Platforms with no Hosts accept any host,
+// for self-hosted networks and websites.
+type SocialPlatform struct {
+	Name    string
+	Label   string
+	Hosts   []string
+	Path    *regexp.Regexp
+	Example string
+}
+
+// SocialPlatforms are the platforms trees can link to, in the order clients
+// are encouraged to list them in pickers.
+var SocialPlatforms = []SocialPlatform{
+	{Name: "x", Label: "X", Hosts: []string{"x.com", "twitter.com"}, Path: regexp.MustCompile(`^/[A-Za-z0-9_]{1,15}/?$`), Example: "https://x.com/handle"},
+	{Name: "instagram", Label: "Instagram", Hosts: []string{"instagram.com"}, Path: regexp.MustCompile(`^/[A-Za-z0-9._]{1,30}/?$`), Example: "https://instagram.com/handle"},
+	{Name: "github", Label: "GitHub", Hosts: []string{"github.com"}, Path: regexp.MustCompile(`^/[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})/?$`), Example: "https://github.com/user"},
+	{Name: "farcaster", Label: "Farcaster", Hosts: []string{"farcaster.xyz", "warpcast.com"}, Path: regexp.MustCompile(`^/[a-z0-9][a-z0-9.-]{0,31}/?$`), Example: "https://farcaster.xyz/handle"},
+	{Name: "lens", Label: "Lens", Hosts: []string{"hey.xyz", "lens.xyz", "orb.club"}, Path: regexp.MustCompile(`^/(?:u/)?[a-z0-9_]{1,31}(?:\.lens)?/?$`), Example: "https://hey.xyz/u/handle"},
+	{Name: "youtube", Label: "YouTube", Hosts: []string{"youtube.com"}, Path: regexp.MustCompile(`^/(?:@[A-Za-z0-9._-]{1,100}|c/[A-Za-z0-9._-]{1,100}|channel/UC[A-Za-z0-9_-]{22})/?$`), Example: "https://youtube.com/@handle"},
+	{Name: "tiktok", Label: "TikTok", Hosts: []string{"tiktok.com"}, Path: regexp.MustCompile(`^/@[A-Za-z0-9._]{1,24}/?$`), Example: "https://tiktok.com/@handle"},
+	{Name: "linkedin", Label: "LinkedIn", Hosts: []string{"linkedin.com"}, Path: regexp.MustCompile(`^/(?:in|company)/[A-Za-z0-9_%-]{1,100}/?$`), Example: "https://linkedin.com/in/name"},
+	{Name: "telegram", Label: "Telegram", Hosts: []string{"t.me"}, Path: regexp.MustCompile(`^/[A-Za-z0-9_]{5,32}/?$`), Example: "https://t.me/handle"},
+	{Name: "discord", Label: "Discord", Hosts: []string{"discord.gg", "discord.com"}, Path: regexp.MustCompile(`^/(?:invite/)?[A-Za-z0-9-]{2,32}/?$`), Example: "https://discord.gg/invite-code"},
+	{Name: "twitch", Label: "Twitch", Hosts: []string{"twitch.tv"}, Path: regexp.MustCompile(`^/[A-Za-z0-9_]{3,25}/?$`), Example: "https://twitch.tv/handle"},
+	{Name: "mastodon", Label: "Mastodon", Path: regexp.MustCompile(`^/@[A-Za-z0-9_]{1,30}/?$`), Example: "https://mastodon.social/@handle"},
+	{Name: SocialPlatformEmail, Label: "Email", Example: "mailto:you@example.com"},
+	{Name: SocialPlatformWeb, Label: "Website", Example: "https://example.com"},
+}
+
+var socialPlatformsByName = func() map[string]*SocialPlatform {
+	m := make(map[string]*SocialPlatform, len(SocialPlatforms))
+	for i := range SocialPlatforms {
+		m[SocialPlatforms[i].Name] = &SocialPlatforms[i]
+	}
+	return m
+}()
+
+// NormalizeSocialURL checks that raw is a profile URL on the named platform
+// and returns it in a canonical form: https, lowercase host without "www.",
+// and no query or fragment, which on profile URLs are tracking parameters.
+// Websites keep their scheme and query. Email takes a mailto: URL or a bare
+// address.
+func NormalizeSocialURL(platform, raw string) (string, error) {
+	p, ok := socialPlatformsByName[platform]
+	if !ok {
+		return "", ErrSocialPlatform
+	}
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > SocialURLMaxLength {
+		return "", ErrSocialURL
+	}
+
+	if p.Name == SocialPlatformEmail {
+		address := strings.TrimPrefix(raw, "mailto:")
+		local, domain, found := strings.Cut(address, "@")
+		if !found || local == "" || !strings.Contains(domain, ".") || strings.ContainsAny(address, " ?#<>\"") {
+			return "", ErrSocialURL
+		}
+		return "mailto:" + address, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil || u.Host == "" {
+		return "", ErrSocialURL
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "https" && scheme != "http" {
+		return "", ErrSocialURL
+	}
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != "443" && port != "80" {
+		return "", ErrSocialURL
+	}
+
+	if p.Name == SocialPlatformWeb {
+		if !strings.Contains(host, ".") {
+			return "", ErrSocialURL
+		}
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+		u.Fragment = ""
+		return u.String(), nil
+	}
+
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "www."), "m.")
+	if len(p.Hosts) > 0 && !containsString(p.Hosts, host) {
+		return "", fmt.Errorf("%s links must be on %s", p.Label, strings.Join(p.Hosts, " or "))
+	}
+	if len(p.Hosts) == 0 && !strings.Contains(host, ".") {
+		return "", ErrSocialURL
+	}
+	if !p.Path.MatchString(u.EscapedPath()) {
+		return "", fmt.Errorf("not a profile URL for %s, expected something like %s", p.Label, p.Example)
+	}
+	return "https://" + host + strings.TrimSuffix(u.EscapedPath(), "/"), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
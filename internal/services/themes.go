@@ -0,0 +1,389 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultThemePreset = "classic"
+
+	// Minimum WCAG contrast ratios: text needs 4.5:1 (AA for body text);
+	// button outlines, which only have to be seen, need 3:1.
+	ThemeTextContrast    = 4.5
+	ThemeOutlineContrast = 3.0
+
+	ThemeBackgroundColor    = "color"
+	ThemeBackgroundGradient = "gradient"
+	ThemeBackgroundImage    = "image"
+
+	ThemeButtonFill    = "fill"
+	ThemeButtonOutline = "outline"
+	ThemeButtonShadow  = "shadow"
+
+	ThemeProblemInvalid       = "invalid"
+	ThemeProblemUnknownPreset = "unknown_preset"
+	ThemeProblemLowContrast   = "low_contrast"
+)
+
+var (
+	ThemeFontFamilies = []string{"Inter", "Roboto", "Poppins", "Lora", "Playfair Display", "Space Grotesk", "JetBrains Mono", "system-ui"}
+	ThemeButtonShapes = []string{"square", "rounded", "pill"}
+	ThemeButtonStyles = []string{ThemeButtonFill, ThemeButtonOutline, ThemeButtonShadow}
+	ThemeLayouts      = []string{"list", "grid", "compact"}
+	ThemeBackgrounds  = []string{ThemeBackgroundColor, ThemeBackgroundGradient, ThemeBackgroundImage}
+)
+
+// Theme is a tree's complete appearance, as rendered. Colors are #rrggbb.
+type Theme struct {
+	Preset     string          `json:"preset"`
+	Colors     ThemeColors     `json:"colors"`
+	Fonts      ThemeFonts      `json:"fonts"`
+	Button     ThemeButton     `json:"button"`
+	Background ThemeBackground `json:"background"`
+	Layout     string          `json:"layout"`
+}
+
+type ThemeColors struct {
+	Background string `json:"background"`
+	Text       string `json:"text"`
+	Button     string `json:"button"`
+	ButtonText string `json:"button_text"`
+	Accent     string `json:"accent"`
+}
+
+type ThemeFonts struct {
+	Heading string `json:"heading"`
+	Body    string `json:"body"`
+}
+
+type ThemeButton struct {
+	Shape string `json:"shape"`
+	Style string `json:"style"`
+}
+
+// ThemeBackground is drawn behind the page. A color background is
+// colors.background; image backgrounds are dimmed towards colors.background,
+// which is also shown while the image loads, so text contrast is checked
+// against it.
+type ThemeBackground struct {
+	Type     string         `json:"type"`
+	Gradient *ThemeGradient `json:"gradient,omitempty"`
+	ImageURL string         `json:"image_url,omitempty"`
+}
+
+type ThemeGradient struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Angle int    `json:"angle"`
+}
+
+// ThemeOverrides change parts of a preset. Unset fields keep the preset's
+// value; a background replaces the preset's background as a whole.
+type ThemeOverrides struct {
+	Colors *struct {
+		Background *string `json:"background,omitempty"`
+		Text       *string `json:"text,omitempty"`
+		Button     *string `json:"button,omitempty"`
+		ButtonText *string `json:"button_text,omitempty"`
+		Accent     *string `json:"accent,omitempty"`
+	} `json:"colors,omitempty"`
+	Fonts *struct {
+		Heading *string `json:"heading,omitempty"`
+		Body    *string `json:"body,omitempty"`
+	} `json:"fonts,omitempty"`
+	Button *struct {
+		Shape *string `json:"shape,omitempty"`
+		Style *string `json:"style,omitempty"`
+	} `json:"button,omitempty"`
+	Background *ThemeBackground `json:"background,omitempty"`
+	Layout     *string          `json:"layout,omitempty"`
+}
+
+// ThemeProblem is one reason a theme was rejected. Field is a dotted path
+// such as "overrides.colors.text".
+type ThemeProblem struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// ThemePreset is a named theme offered in the catalog.
+type ThemePreset struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Theme Theme  `json:"theme"`
+}
+
+// ThemePresets are listed by GET /themes. Every preset passes the contrast
+// checks on its own.
+var ThemePresets = []ThemePreset{
+	{Name: "classic", Label: "Classic", Theme: Theme{
+		Colors:     ThemeColors{Background: "#ffffff", Text: "#1f2933", Button: "#1f2933", ButtonText: "#ffffff", Accent: "#2563eb"},
+		Fonts:      ThemeFonts{Heading: "Inter", Body: "Inter"},
+		Button:     ThemeButton{Shape: "rounded", Style: ThemeButtonFill},
+		Background: ThemeBackground{Type: ThemeBackgroundColor},
+		Layout:     "list",
+	}},
+	{Name: "midnight", Label: "Midnight", Theme: Theme{
+		Colors:     ThemeColors{Background: "#0f172a", Text: "#f1f5f9", Button: "#f1f5f9", ButtonText: "#0f172a", Accent: "#38bdf8"},
+		Fonts:      ThemeFonts{Heading: "Space Grotesk", Body: "Inter"},
+		Button:     ThemeButton{Shape: "pill", Style: ThemeButtonOutline},
+		Background: ThemeBackground{Type: ThemeBackgroundColor},
+		Layout:     "list",
+	}},
+	{Name: "sunset", Label: "Sunset", Theme: Theme{
+		Colors: ThemeColors{Background: "#7c2d12", Text: "#ffffff", Button: "#ffffff", ButtonText: "#7c2d12", Accent: "#fde68a"},
+		Fonts:  ThemeFonts{Heading: "Poppins", Body: "Poppins"},
+		Button: ThemeButton{Shape: "pill", Style: ThemeButtonFill},
+		Background: ThemeBackground{Type: ThemeBackgroundGradient, Gradient: &ThemeGradient{
+			From: "#9a3412", To: "#831843", Angle: 160,
+		}},
+		Layout: "list",
+	}},
+	{Name: "forest", Label: "Forest", Theme: Theme{
+		Colors:     ThemeColors{Background: "#ecfdf5", Text: "#064e3b", Button: "#065f46", ButtonText: "#ecfdf5", Accent: "#047857"},
+		Fonts:      ThemeFonts{Heading: "Lora", Body: "Inter"},
+		Button:     ThemeButton{Shape: "rounded", Style: ThemeButtonShadow},
+		Background: ThemeBackground{Type: ThemeBackgroundColor},
+		Layout:     "list",
+	}},
+	{Name: "mono", Label: "Mono", Theme: Theme{
+		Colors:     ThemeColors{Background: "#fafafa", Text: "#111111", Button: "#111111", ButtonText: "#fafafa", Accent: "#111111"},
+		Fonts:      ThemeFonts{Heading: "JetBrains Mono", Body: "JetBrains Mono"},
+		Button:     ThemeButton{Shape: "square", Style: ThemeButtonOutline},
+		Background: ThemeBackground{Type: ThemeBackgroundColor},
+		Layout:     "compact",
+	}},
+	{Name: "neon", Label: "Neon", Theme: Theme{
+		Colors: ThemeColors{Background: "#09090b", Text: "#fafafa", Button: "#a3e635", ButtonText: "#09090b", Accent: "#e879f9"},
+		Fonts:  ThemeFonts{Heading: "Space Grotesk", Body: "Space Grotesk"},
+		Button: ThemeButton{Shape: "rounded", Style: ThemeButtonFill},
+		Background: ThemeBackground{Type: ThemeBackgroundGradient, Gradient: &ThemeGradient{
+			From: "#09090b", To: "#1e1b4b", Angle: 180,
+		}},
+		Layout: "grid",
+	}},
+}
+
+var themePresetsByName = func() map[string]Theme {
+	m := make(map[string]Theme, len(ThemePresets))
+	for i := range ThemePresets {
+		ThemePresets[i].Theme.Preset = ThemePresets[i].Name
+		m[ThemePresets[i].Name] = ThemePresets[i].Theme
+	}
+	return m
+}()
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ParseThemeOverrides decodes stored or submitted overrides. Unknown fields
+// are rejected so typos do not silently do nothing.
+func ParseThemeOverrides(raw []byte) (ThemeOverrides, error) {
+	var o ThemeOverrides
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return o, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&o); err != nil {
+		return ThemeOverrides{}, err
+	}
+	return o, nil
+}
+
+// ResolveTheme applies overrides to the named preset and validates the
+// result, including the contrast between text and what it is drawn on. The
+// returned theme has colors normalized to lowercase #rrggbb and is only
+// meaningful when there are no problems.
+func ResolveTheme(preset string, o ThemeOverrides) (Theme, []ThemeProblem) {
+	base, ok := themePresetsByName[preset]
+	if !ok {
+		return Theme{}, []ThemeProblem{{
+			Field:   "preset",
+			Code:    ThemeProblemUnknownPreset,
+			Message: fmt.Sprintf("unknown theme preset %q", preset),
+		}}
+	}
+
+	t := base
+	if t.Background.Gradient != nil {
+		g := *t.Background.Gradient
+		t.Background.Gradient = &g
+	}
+	if o.Colors != nil {
+		setString(&t.Colors.Background, o.Colors.Background)
+		setString(&t.Colors.Text, o.Colors.Text)
+		setString(&t.Colors.Button, o.Colors.Button)
+		setString(&t.Colors.ButtonText, o.Colors.ButtonText)
+		setString(&t.Colors.Accent, o.Colors.Accent)
+	}
+	if o.Fonts != nil {
+		setString(&t.Fonts.Heading, o.Fonts.Heading)
+		setString(&t.Fonts.Body, o.Fonts.Body)
+	}
+	if o.Button != nil {
+		setString(&t.Button.Shape, o.Button.Shape)
+		setString(&t.Button.Style, o.Button.Style)
+	}
+	if o.Background != nil {
+		t.Background = *o.Background
+		if t.Background.Gradient != nil {
+			g := *t.Background.Gradient
+			t.Background.Gradient = &g
+		}
+	}
+	setString(&t.Layout, o.Layout)
+
+	var problems []ThemeProblem
+	invalid := func(field, message string) {
+		problems = append(problems, ThemeProblem{Field: "overrides." + field, Code: ThemeProblemInvalid, Message: message})
+	}
+	color := func(field string, c *string) {
+		if !hexColor.MatchString(*c) {
+			invalid(field, field+" must be a hex color like #1a2b3c")
+			return
+		}
+		*c = normalizeHexColor(*c)
+	}
+	oneOf := func(field, value string, allowed []string) {
+		if !containsString(allowed, value) {
+			invalid(field, field+" must be one of "+strings.Join(allowed, ", "))
+		}
+	}
+
+	color("colors.background", &t.Colors.Background)
+	color("colors.text", &t.Colors.Text)
+	color("colors.button", &t.Colors.Button)
+	color("colors.button_text", &t.Colors.ButtonText)
+	color("colors.accent", &t.Colors.Accent)
+	oneOf("fonts.heading", t.Fonts.Heading, ThemeFontFamilies)
+	oneOf("fonts.body", t.Fonts.Body, ThemeFontFamilies)
+	oneOf("button.shape", t.Button.Shape, ThemeButtonShapes)
+	oneOf("button.style", t.Button.Style, ThemeButtonStyles)
+	oneOf("layout", t.Layout, ThemeLayouts)
+
+	switch t.Background.Type {
+	case ThemeBackgroundColor:
+		t.Background.Gradient = nil
+		t.Background.ImageURL = ""
+	case ThemeBackgroundGradient:
+		t.Background.ImageURL = ""
+		if t.Background.Gradient == nil {
+			invalid("background.gradient", "a gradient background needs a gradient")
+			break
+		}
+		color("background.gradient.from", &t.Background.Gradient.From)
+		color("background.gradient.to", &t.Background.Gradient.To)
+		if t.Background.Gradient.Angle < 0 || t.Background.Gradient.Angle > 359 {
+			invalid("background.gradient.angle", "background.gradient.angle must be between 0 and 359")
+		}
+	case ThemeBackgroundImage:
+		t.Background.Gradient = nil
+		u, err := NormalizeAvatarURL(t.Background.ImageURL)
+		if err != nil || u == "" {
+			invalid("background.image_url", "background.image_url must be an https:// or ipfs:// URL")
+			break
+		}
+		t.Background.ImageURL = u
+	default:
+		invalid("background.type", "background.type must be one of "+strings.Join(ThemeBackgrounds, ", "))
+	}
+
+	// Contrast only means something once every color is valid.
+	if len(problems) > 0 {
+		return t, problems
+	}
+	return t, checkThemeContrast(t)
+}
+
+func checkThemeContrast(t Theme) []ThemeProblem {
+	var problems []ThemeProblem
+	check := func(field, what, fg, bg string, min float64) {
+		if ratio := ContrastRatio(fg, bg); ratio < min {
+			problems = append(problems, ThemeProblem{
+				Field:   "overrides." + field,
+				Code:    ThemeProblemLowContrast,
+				Message: fmt.Sprintf("%s has a contrast ratio of %.2f:1, at least %.1f:1 is needed", what, ratio, min),
+			})
+		}
+	}
+
+	// Text sits on every color the background shows.
+	backgrounds := []string{t.Colors.Background}
+	if t.Background.Type == ThemeBackgroundGradient {
+		backgrounds = []string{t.Background.Gradient.From, t.Background.Gradient.To}
+	}
+	for _, bg := range backgrounds {
+		check("colors.text", "text on the background", t.Colors.Text, bg, ThemeTextContrast)
+		check("colors.accent", "accent on the background", t.Colors.Accent, bg, ThemeTextContrast)
+		if t.Button.Style == ThemeButtonOutline {
+			// Outline buttons draw their label and border in the button
+			// color straight onto the page.
+			check("colors.button", "outline button on the background", t.Colors.Button, bg, ThemeTextContrast)
+		} else {
+			check("colors.button", "button on the background", t.Colors.Button, bg, ThemeOutlineContrast)
+		}
+	}
+	if t.Button.Style != ThemeButtonOutline {
+		check("colors.button_text", "button text on the button", t.Colors.ButtonText, t.Colors.Button, ThemeTextContrast)
+	}
+	return dedupeThemeProblems(problems)
+}
+
+// dedupeThemeProblems keeps the first problem per field; with a gradient the
+// same color can fail against both ends.
+func dedupeThemeProblems(problems []ThemeProblem) []ThemeProblem {
+	seen := make(map[string]bool, len(problems))
+	out := problems[:0]
+	for _, p := range problems {
+		if seen[p.Field] {
+			continue
+		}
+		seen[p.Field] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+// ContrastRatio is the WCAG 2 contrast ratio between two #rrggbb colors,
+// from 1 to 21.
+func ContrastRatio(a, b string) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func relativeLuminance(hex string) float64 {
+	var r, g, b int
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	channel := func(v int) float64 {
+		c := float64(v) / 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// normalizeHexColor turns #abc and #ABCDEF into #aabbcc and #abcdef.
+func normalizeHexColor(c string) string {
+	c = strings.ToLower(c)
+	if len(c) == 4 {
+		return "#" + strings.Repeat(c[1:2], 2) + strings.Repeat(c[2:3], 2) + strings.Repeat(c[3:4], 2)
+	}
+	return c
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
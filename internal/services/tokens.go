@@ -0,0 +1,29 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateOpaqueToken returns a random token to hand to the client and the
+// hash to persist. Only the hash should ever be stored.
+func GenerateOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateTokenFamily returns an identifier shared by a chain of rotated refresh tokens.
+func GenerateTokenFamily() (string, error) {
+	return randomHex(16)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"chainhub-api/internal/testutil"
+)
+
+func TestValidateTOTPAcceptsAuthenticatorCodes(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	for _, offset := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+		code, err := testutil.TOTPCode(secret, now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := ValidateTOTP(secret, code, now); !ok {
+			t.Errorf("code from %v away rejected", offset)
+		}
+	}
+
+	code, err := testutil.TOTPCode(secret, now.Add(-2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := testutil.TOTPCode(secret, now); code != current {
+		if _, ok := ValidateTOTP(secret, code, now); ok {
+			t.Error("code from two minutes ago accepted")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"chainhub-api/internal/models"
+)
+
+// TreeDiff lists what changed between two snapshots of a tree.
+type TreeDiff struct {
+	Fields       []FieldChange         `json:"fields"`
+	LinksAdded   []models.SnapshotLink `json:"links_added"`
+	LinksRemoved []models.SnapshotLink `json:"links_removed"`
+	LinksChanged []LinkChange          `json:"links_changed"`
+}
+
+// FieldChange is a value that differs. From and To are strings for text
+// fields and JSON values for socials and the theme.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// LinkChange is a link present on both sides with different values.
+type LinkChange struct {
+	ID      int64         `json:"id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffTreeSnapshots compares from with to. Links are matched by ID, so a
+// link that was edited shows as changed rather than removed and added.
+func DiffTreeSnapshots(from, to models.TreeSnapshot) TreeDiff {
+	diff := TreeDiff{
+		Fields:       []FieldChange{},
+		LinksAdded:   []models.SnapshotLink{},
+		LinksRemoved: []models.SnapshotLink{},
+		LinksChanged: []LinkChange{},
+	}
+
+	text := func(field, a, b string) {
+		if a != b {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+	text("title", from.Title, to.Title)
+	text("display_name", from.DisplayName, to.DisplayName)
+	text("bio", from.Bio, to.Bio)
+	text("avatar_url", from.AvatarURL, to.AvatarURL)
+	if !SameJSON(from.Socials, to.Socials) {
+		diff.Fields = append(diff.Fields, FieldChange{Field: "socials", From: from.Socials, To: to.Socials})
+	}
+	text("theme_preset", from.ThemePreset, to.ThemePreset)
+	if !SameJSON(from.ThemeOverrides, to.ThemeOverrides) {
+		diff.Fields = append(diff.Fields, FieldChange{Field: "theme_overrides", From: from.ThemeOverrides, To: to.ThemeOverrides})
+	}
+
+	before := make(map[int64]models.SnapshotLink, len(from.Links))
+	for _, link := range from.Links {
+		before[link.ID] = link
+	}
+	after := make(map[int64]bool, len(to.Links))
+	for _, link := range to.Links {
+		after[link.ID] = true
+		old, ok := before[link.ID]
+		if !ok {
+			diff.LinksAdded = append(diff.LinksAdded, link)
+			continue
+		}
+		if changes := diffLinks(old, link); len(changes) > 0 {
+			diff.LinksChanged = append(diff.LinksChanged, LinkChange{ID: link.ID, Changes: changes})
+		}
+	}
+	for _, link := range from.Links {
+		if !after[link.ID] {
+			diff.LinksRemoved = append(diff.LinksRemoved, link)
+		}
+	}
+	return diff
+}
+
+func diffLinks(a, b models.SnapshotLink) []FieldChange {
+	var changes []FieldChange
+	if a.Title != b.Title {
+		changes = append(changes, FieldChange{Field: "title", From: a.Title, To: b.Title})
+	}
+	if a.URL != b.URL {
+		changes = append(changes, FieldChange{Field: "url", From: a.URL, To: b.URL})
+	}
+	if a.Position != b.Position {
+		changes = append(changes, FieldChange{Field: "position", From: a.Position, To: b.Position})
+	}
+	if a.IsActive != b.IsActive {
+		changes = append(changes, FieldChange{Field: "is_active", From: a.IsActive, To: b.IsActive})
+	}
+	return changes
+}
+
+// SameJSON compares two values as JSON, ignoring formatting and key order,
+// which the database does not keep for JSONB columns.
+func SameJSON(a, b interface{}) bool {
+	ca, errA := canonicalJSON(a)
+	cb, errB := canonicalJSON(b)
+	return errA == nil && errB == nil && bytes.Equal(ca, cb)
+}
+
+func canonicalJSON(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	DisplayNameMaxLength = 50
+	BioMaxLength         = 300
+	BioMaxLines          = 6
+	AvatarURLMaxLength   = 2048
+)
+
+var (
+	ErrDisplayNameLength = fmt.Errorf("display name must be at most %d characters", DisplayNameMaxLength)
+	ErrDisplayNameFormat = errors.New("display name cannot contain control characters, line breaks or text direction overrides")
+	ErrBioLength         = fmt.Errorf("bio must be at most %d characters", BioMaxLength)
+	ErrBioLines          = fmt.Errorf("bio must be at most %d lines", BioMaxLines)
+	ErrAvatarURL         = errors.New("avatar must be an https:// or ipfs:// URL")
+	ErrAvatarURLLength   = fmt.Errorf("avatar URL must be at most %d characters", AvatarURLMaxLength)
+)
+
+// NormalizeDisplayName trims and NFC-normalizes a display name. An empty name
+// is allowed; pages then fall back to the tree title.
+func NormalizeDisplayName(raw string) (string, error) {
+	name := norm.NFC.String(strings.TrimSpace(raw))
+	if utf8.RuneCountInString(name) > DisplayNameMaxLength {
+		return "", ErrDisplayNameLength
+	}
+	for _, r := range name {
+		if isHiddenRune(r) {
+			return "", ErrDisplayNameFormat
+		}
+	}
+	return name, nil
+}
+
+// SanitizeBio cleans up bio Markdown before it is stored: line endings become
+// \n, other control characters and trailing spaces are dropped, and runs of
+// blank lines shrink to one. Length limits apply to the result. RenderBio
+// turns it into HTML.
+func SanitizeBio(raw string) (string, error) {
+	raw = strings.ReplaceAll(norm.NFC.String(raw), "\r\n", "\n")
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return ' '
+			}
+			if isHiddenRune(r) {
+				return -1
+			}
+			return r
+		}, line)
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			if blank || len(lines) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	if blank {
+		lines = lines[:len(lines)-1]
+	}
+
+	bio := strings.Join(lines, "\n")
+	if utf8.RuneCountInString(bio) > BioMaxLength {
+		return "", ErrBioLength
+	}
+	if len(lines) > BioMaxLines {
+		return "", ErrBioLines
+	}
+	return bio, nil
+}
+
+// NormalizeAvatarURL checks an avatar reference: an https URL, or an ipfs://
+// CID for images pinned to IPFS. An empty string clears the avatar.
+func NormalizeAvatarURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > AvatarURLMaxLength {
+		return "", ErrAvatarURLLength
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil || u.Host == "" {
+		return "", ErrAvatarURL
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https", "ipfs":
+	default:
+		return "", ErrAvatarURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// isHiddenRune reports control characters, line and paragraph separators and
+// invisible formatting characters such as text direction overrides, which
+// could be used to disguise text. The zero-width joiner is allowed because
+// emoji sequences need it.
+func isHiddenRune(r rune) bool {
+	return unicode.IsControl(r) ||
+		unicode.In(r, unicode.Zl, unicode.Zp) ||
+		(unicode.Is(unicode.Cf, r) && r != '\u200d')
+}
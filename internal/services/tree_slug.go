@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	TreeSlugMinLength = 3
+	TreeSlugMaxLength = 40
+)
+
+var ErrTreeSlugFormat = fmt.Errorf(
+	"slug must be %d to %d lowercase letters, digits or single '-' between them",
+	TreeSlugMinLength,
+	TreeSlugMaxLength,
+)
+
+// ValidateTreeSlug checks a slug chosen by a user. Slugs are public URLs, so
+// they are kept to lowercase ASCII.
+func ValidateTreeSlug(slug string) error {
+	if len(slug) < TreeSlugMinLength || len(slug) > TreeSlugMaxLength {
+		return ErrTreeSlugFormat
+	}
+	prev := byte('-')
+	for i := 0; i < len(slug); i++ {
+		c := slug[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && prev != '-':
+		default:
+			return ErrTreeSlugFormat
+		}
+		prev = c
+	}
+	if prev == '-' {
+		return ErrTreeSlugFormat
+	}
+	return nil
+}
+
+// SlugifyTreeName turns a name into the start of a slug: lowercase ASCII
+// letters and digits with accents dropped, everything else collapsed into
+// '-'. The result may still be too short for ValidateTreeSlug.
+func SlugifyTreeName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			if b.Len() >= TreeSlugMaxLength-7 {
+				break
+			}
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// GenerateTreeSlug returns base with a random suffix, for when base is taken
+// or too short.
+func GenerateTreeSlug(base string) (string, error) {
+	suffix, err := randomHex(3)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		base = "tree"
+	}
+	return base + "-" + suffix, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"chainhub-api/internal/repo"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 30
+)
+
+var (
+	ErrUsernameLength     = fmt.Errorf("username must be %d to %d characters", UsernameMinLength, UsernameMaxLength)
+	ErrUsernameCharacters = errors.New("username may only contain letters, digits, '_', '.' and '-'")
+	ErrUsernameFormat     = errors.New("username must start and end with a letter or digit and cannot repeat '_', '.' or '-'")
+	ErrUsernameReserved   = errors.New("username is reserved")
+)
+
+// UsernamePolicy validates usernames and computes the skeleton used to keep
+// look-alike names unique.
+type UsernamePolicy struct {
+	reserved map[string]bool
+}
+
+// NewUsernamePolicy rejects the given names and anything that looks like them.
+func NewUsernamePolicy(reserved []string) *UsernamePolicy {
+	p := &UsernamePolicy{reserved: make(map[string]bool, len(reserved))}
+	for _, name := range reserved {
+		p.reserved[UsernameSkeleton(name)] = true
+	}
+	return p
+}
+
+// Validate normalizes raw and checks it against the policy. It returns the
+// username to store, with its case kept for display, and its skeleton.
+func (p *UsernamePolicy) Validate(raw string) (string, string, error) {
+	username := norm.NFKC.String(strings.TrimSpace(raw))
+
+	length := utf8.RuneCountInString(username)
+	if length < UsernameMinLength || length > UsernameMaxLength {
+		return "", "", ErrUsernameLength
+	}
+
+	var prev rune
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+		case r == '_' || r == '.' || r == '-':
+			if i == 0 || isUsernameSeparator(prev) {
+				return "", "", ErrUsernameFormat
+			}
+		default:
+			return "", "", ErrUsernameCharacters
+		}
+		prev = r
+	}
+	if isUsernameSeparator(prev) {
+		return "", "", ErrUsernameFormat
+	}
+
+	// "ADMIN" looks like "admin" once lower-cased, though its skeleton reads
+	// "I" as "l".
+	skeleton := UsernameSkeleton(username)
+	if p.reserved[skeleton] || p.reserved[UsernameSkeleton(strings.ToLower(username))] {
+		return "", "", ErrUsernameReserved
+	}
+	return username, skeleton, nil
+}
+
+func isUsernameSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-'
+}
+
+// UsernameSkeleton maps a username to a form in which names that look alike
+// are equal, in the spirit of the UTS #39 skeleton: case and accents are
+// dropped and common look-alike characters from other scripts are folded to
+// Latin. Capitals that look like a different lower-case letter ("I" for "l",
+// Greek "Ν" for "N") are mapped before case is folded; everything else is
+// compared in lower case. The table covers the confusables that matter for
+// short handles rather than the full Unicode data.
+func UsernameSkeleton(username string) string {
+	var upper strings.Builder
+	for _, r := range norm.NFKC.String(username) {
+		if mapped, ok := upperConfusables[r]; ok {
+			r = mapped
+		}
+		upper.WriteRune(r)
+	}
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(upper.String())) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if mapped, ok := confusables[r]; ok {
+			b.WriteRune(mapped)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return confusableSequences.Replace(b.String())
+}
+
+// confusableSequences folds multi-letter look-alikes after single letters.
+var confusableSequences = strings.NewReplacer("rn", "m", "vv", "w")
+
+// upperConfusables are capitals whose lower case looks like something else,
+// so folding case first would lose the resemblance.
+var upperConfusables = map[rune]rune{
+	'I': 'l', 'Ι': 'l', 'І': 'l', 'Ӏ': 'l', 'Ν': 'n', 'Υ': 'y',
+}
+
+// confusables is applied after lower-casing. Letters whose capital is the
+// look-alike (Cyrillic "н" for "Н", Greek "τ" for "Τ") are listed under their
+// lower case.
+var confusables = map[rune]rune{
+	// Latin and digits
+	'0': 'o', '1': 'l', 'ı': 'i', 'ɩ': 'i', 'ɡ': 'g', 'ʏ': 'y',
+
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'н': 'h',
+	'і': 'i', 'ј': 'j', 'к': 'k', 'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p',
+	'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'ү': 'y', 'ԝ': 'w', 'х': 'x',
+
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'h', 'ι': 'i', 'κ': 'k', 'μ': 'm',
+	'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'γ': 'y', 'χ': 'x',
+	'ζ': 'z',
+
+	// Armenian
+	'հ': 'h', 'ո': 'n', 'օ': 'o', 'զ': 'q', 'ս': 'u', 'ց': 'g',
+}
+
+// BackfillUsernameSkeletons computes skeletons for usernames created before
+// they existed. An account whose name looks like an older one cannot take the
+// skeleton, and leaving it without one would exempt it from the look-alike
+// check, so the backfill fails and lists those accounts; rename them and start
+// again (`make check-emails` shows both sides). Everything else is filled in
+// first.
+func BackfillUsernameSkeletons(db *sql.DB) error {
+	pending, err := repo.ListUsersWithoutUsernameSkeleton(db)
+	if err != nil {
+		return err
+	}
+	var lookAlikes strings.Builder
+	for _, p := range pending {
+		err := repo.SetUsernameSkeleton(db, p.UserID, UsernameSkeleton(p.Username))
+		if errors.Is(err, repo.ErrDuplicate) {
+			fmt.Fprintf(&lookAlikes, "\n  user %d\t%s", p.UserID, p.Username)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	redirects, err := repo.ListRedirectsWithoutSkeleton(db)
+	if err != nil {
+		return err
+	}
+	for _, name := range redirects {
+		if err := repo.SetRedirectSkeleton(db, name, UsernameSkeleton(name)); err != nil {
+			return err
+		}
+	}
+
+	if lookAlikes.Len() > 0 {
+		return fmt.Errorf("these usernames look like an older account's; rename them first:%s", lookAlikes.String())
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUsernamePolicyRejectsReservedNamesInAnyCase(t *testing.T) {
+	policy := NewUsernamePolicy([]string{"admin", "login", "signup", "api", "links"})
+
+	for _, name := range []string{
+		"admin", "Admin", "ADMIN", "AdMiN",
+		"LOGIN", "SIGNUP", "API", "LINKS",
+		"аdmin", "АDMIN", // Cyrillic "а" and "А"
+	} {
+		if _, _, err := policy.Validate(name); !errors.Is(err, ErrUsernameReserved) {
+			t.Errorf("Validate(%q) = %v, want ErrUsernameReserved", name, err)
+		}
+	}
+}
+
+func TestUsernameSkeletonFoldsCase(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"Bill", "bill"},
+		{"ΤΕΑΜ", "team"}, // Greek capitals
+		{"НОМЕ", "home"}, // Cyrillic capitals
+	} {
+		if got, want := UsernameSkeleton(pair[0]), UsernameSkeleton(pair[1]); got != want {
+			t.Errorf("UsernameSkeleton(%q) = %q, want %q", pair[0], got, want)
+		}
+	}
+}
+
+// Capitals that look like a different lower-case letter must be mapped
+// before case is folded.
+func TestUsernameSkeletonMatchesLookAlikeCapitals(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"aIice", "alice"}, // Latin capital I
+		{"aΙice", "alice"}, // Greek capital iota
+		{"aІice", "alice"}, // Cyrillic capital і
+		{"ΝΕΟ", "neo"},     // Greek capitals
+		{"ΥΑΝ", "yan"},     // Greek capitals
+	} {
+		if got, want := UsernameSkeleton(pair[0]), UsernameSkeleton(pair[1]); got != want {
+			t.Errorf("UsernameSkeleton(%q) = %q, want %q", pair[0], got, want)
+		}
+	}
+}
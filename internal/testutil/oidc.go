@@ -0,0 +1,216 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const oidcKeyID = "test-key"
+
+// OIDCUser is who the provider says signed in. Nonce, if set, replaces the
+// nonce from the authorization request in the ID token.
+type OIDCUser struct {
+	Subject       string
+	Email         string
+	EmailVerified interface{} // bool, or a string as some providers send it
+	Name          string
+	Nonce         string
+}
+
+// OIDCProvider is a local OpenID Connect provider: discovery, JWKS and a
+// token endpoint that enforces PKCE (S256) and signs RS256 ID tokens. There
+// is no login page; Authorize plays the user approving the request.
+type OIDCProvider struct {
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]oidcGrant
+}
+
+type oidcGrant struct {
+	user          OIDCUser
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+func NewOIDCProvider() (*OIDCProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	p := &OIDCProvider{
+		ClientID:     "chainhub-test",
+		ClientSecret: "chainhub-test-secret",
+		key:          key,
+		grants:       make(map[string]oidcGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	return p, nil
+}
+
+func (p *OIDCProvider) Issuer() string { return p.server.URL }
+
+func (p *OIDCProvider) Close() { p.server.Close() }
+
+// Authorize approves the authorization request at authURL as user and
+// returns the code and state the provider would redirect back with.
+func (p *OIDCProvider) Authorize(authURL string, user OIDCUser) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	if q.Get("client_id") != p.ClientID {
+		return "", "", errors.New("unknown client_id")
+	}
+	if q.Get("response_type") != "code" {
+		return "", "", errors.New("response_type must be code")
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", "", errors.New("PKCE S256 challenge missing")
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	code = hex.EncodeToString(raw)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[code] = oidcGrant{
+		user:          user,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		redirectURI:   q.Get("redirect_uri"),
+	}
+	return code, q.Get("state"), nil
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeProviderJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *OIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeProviderJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": oidcKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	grant, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+	if !ok || r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	nonce := grant.nonce
+	if grant.user.Nonce != "" {
+		nonce = grant.user.Nonce
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.Issuer(),
+		"sub":   grant.user.Subject,
+		"aud":   p.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": nonce,
+	}
+	if grant.user.Email != "" {
+		claims["email"] = grant.user.Email
+	}
+	if grant.user.EmailVerified != nil {
+		claims["email_verified"] = grant.user.EmailVerified
+	}
+	if grant.user.Name != "" {
+		claims["name"] = grant.user.Name
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = oidcKeyID
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeProviderJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + code,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeProviderJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeProviderJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,245 @@
+// Package testutil has stand-ins for the outside parties the API talks to,
+// for use in tests: a software WebAuthn authenticator and a local OpenID
+// Connect provider.
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// Authenticator data flags, WebAuthn §6.1.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// CeremonyOptions is the part of WebAuthn creation or request options that a
+// software authenticator needs.
+type CeremonyOptions struct {
+	Challenge []byte
+	RPID      string
+	UserID    []byte
+}
+
+// ParseCeremonyOptions reads the options the server sends to the browser,
+// either wrapped in "publicKey" or bare.
+func ParseCeremonyOptions(raw []byte) (CeremonyOptions, error) {
+	type options struct {
+		Challenge string `json:"challenge"`
+		RPID      string `json:"rpId"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	var wrapped struct {
+		PublicKey *options `json:"publicKey"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err != nil {
+		return CeremonyOptions{}, err
+	}
+	opts := wrapped.PublicKey
+	if opts == nil {
+		opts = &options{}
+		if err := json.Unmarshal(raw, opts); err != nil {
+			return CeremonyOptions{}, err
+		}
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(opts.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return CeremonyOptions{}, errors.New("options have no valid challenge")
+	}
+	parsed := CeremonyOptions{Challenge: challenge, RPID: opts.RPID}
+	if parsed.RPID == "" {
+		parsed.RPID = opts.RP.ID
+	}
+	if opts.User.ID != "" {
+		if parsed.UserID, err = base64.RawURLEncoding.DecodeString(opts.User.ID); err != nil {
+			return CeremonyOptions{}, err
+		}
+	}
+	return parsed, nil
+}
+
+// Passkey is a discoverable ES256 credential held by a software
+// authenticator. SignCount is what the next assertion reports, less one; set
+// it back to replay an old counter as a cloned key would.
+type Passkey struct {
+	ID         []byte
+	UserHandle []byte
+	RPID       string
+	SignCount  uint32
+
+	key *ecdsa.PrivateKey
+}
+
+// NewPasskey creates a credential for the user and relying party named in
+// creation options.
+func NewPasskey(opts CeremonyOptions) (*Passkey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Passkey{ID: id, UserHandle: opts.UserID, RPID: opts.RPID, key: key}, nil
+}
+
+// Attest answers creation options from origin with a "none" attestation, as
+// navigator.credentials.create would return it.
+func (p *Passkey) Attest(origin string, opts CeremonyOptions) ([]byte, error) {
+	clientData, err := clientDataJSON("webauthn.create", opts.Challenge, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	point, err := p.key.PublicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	xy := point.Bytes()[1:] // uncompressed: 0x04 || x || y
+
+	var coseKey []byte
+	coseKey = cborHead(coseKey, 5, 5)
+	coseKey = cborInt(coseKey, 1) // kty: EC2
+	coseKey = cborInt(coseKey, 2)
+	coseKey = cborInt(coseKey, 3) // alg: ES256
+	coseKey = cborInt(coseKey, -7)
+	coseKey = cborInt(coseKey, -1) // crv: P-256
+	coseKey = cborInt(coseKey, 1)
+	coseKey = cborInt(coseKey, -2)
+	coseKey = cborBytes(coseKey, xy[:32])
+	coseKey = cborInt(coseKey, -3)
+	coseKey = cborBytes(coseKey, xy[32:])
+
+	authData := p.authData(opts.RPID, flagUserPresent|flagUserVerified|flagAttestedData, 0)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(p.ID)))
+	authData = append(authData, p.ID...)
+	authData = append(authData, coseKey...)
+
+	var attestation []byte
+	attestation = cborHead(attestation, 5, 3)
+	attestation = cborText(attestation, "fmt")
+	attestation = cborText(attestation, "none")
+	attestation = cborText(attestation, "attStmt")
+	attestation = cborHead(attestation, 5, 0)
+	attestation = cborText(attestation, "authData")
+	attestation = cborBytes(attestation, authData)
+
+	return json.Marshal(map[string]interface{}{
+		"id":    b64(p.ID),
+		"rawId": b64(p.ID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    b64(clientData),
+			"attestationObject": b64(attestation),
+			"transports":        []string{"internal"},
+		},
+		"clientExtensionResults": map[string]interface{}{},
+	})
+}
+
+// Assert answers request options from origin, as navigator.credentials.get
+// would. Each assertion advances SignCount.
+func (p *Passkey) Assert(origin string, opts CeremonyOptions) ([]byte, error) {
+	return p.AssertAs(origin, opts, p.UserHandle)
+}
+
+// AssertAs is Assert with the user handle replaced, for a credential that
+// claims to belong to someone else.
+func (p *Passkey) AssertAs(origin string, opts CeremonyOptions, userHandle []byte) ([]byte, error) {
+	clientData, err := clientDataJSON("webauthn.get", opts.Challenge, origin)
+	if err != nil {
+		return nil, err
+	}
+	rpID := opts.RPID
+	if rpID == "" {
+		rpID = p.RPID
+	}
+
+	p.SignCount++
+	authData := p.authData(rpID, flagUserPresent|flagUserVerified, p.SignCount)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"id":    b64(p.ID),
+		"rawId": b64(p.ID),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        b64(userHandle),
+		},
+		"clientExtensionResults": map[string]interface{}{},
+	})
+}
+
+func (p *Passkey) authData(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   b64(challenge),
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// The few CBOR encodings (RFC 8949) an attestation object needs.
+
+func cborHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= 0xff:
+		return append(buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	}
+}
+
+func cborInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return cborHead(buf, 1, uint64(-1-n))
+	}
+	return cborHead(buf, 0, uint64(n))
+}
+
+func cborBytes(buf, data []byte) []byte {
+	return append(cborHead(buf, 2, uint64(len(data))), data...)
+}
+
+func cborText(buf []byte, s string) []byte {
+	return append(cborHead(buf, 3, uint64(len(s))), s...)
+}
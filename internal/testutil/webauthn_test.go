@@ -0,0 +1,109 @@
+package testutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const testOrigin = "http://localhost:3000"
+
+type testUser struct {
+	id          []byte
+	credentials []webauthn.Credential
+}
+
+func (u *testUser) WebAuthnID() []byte                         { return u.id }
+func (u *testUser) WebAuthnName() string                       { return "alice" }
+func (u *testUser) WebAuthnDisplayName() string                { return "alice" }
+func (u *testUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// TestPasskeyCeremonies checks the software authenticator against the
+// library the API verifies passkeys with.
+func TestPasskeyCeremonies(t *testing.T) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "ChainHub",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &testUser{id: []byte("user-handle-1")}
+
+	creation, session, err := wa.BeginRegistration(user, webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := mustOptions(t, creation)
+	passkey, err := NewPasskey(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestation, err := passkey.Attest(testOrigin, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedCreation, err := protocol.ParseCredentialCreationResponseBytes(attestation)
+	if err != nil {
+		t.Fatalf("parse attestation: %v", err)
+	}
+	credential, err := wa.CreateCredential(user, *session, parsedCreation)
+	if err != nil {
+		t.Fatalf("create credential: %v", err)
+	}
+	user.credentials = append(user.credentials, *credential)
+
+	login := func(p *Passkey) (*webauthn.Credential, error) {
+		assertion, session, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := mustOptions(t, assertion)
+		body, err := p.Assert(testOrigin, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+		if err != nil {
+			t.Fatalf("parse assertion: %v", err)
+		}
+		_, cred, err := wa.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			return user, nil
+		}, *session, parsed)
+		return cred, err
+	}
+
+	cred, err := login(passkey)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if cred.Authenticator.CloneWarning || cred.Authenticator.SignCount != 1 {
+		t.Fatalf("sign count = %d, clone warning = %t; want 1, false", cred.Authenticator.SignCount, cred.Authenticator.CloneWarning)
+	}
+	user.credentials[0].Authenticator.SignCount = cred.Authenticator.SignCount
+
+	passkey.SignCount = 0
+	cred, err = login(passkey)
+	if err != nil {
+		t.Fatalf("replayed login: %v", err)
+	}
+	if !cred.Authenticator.CloneWarning {
+		t.Fatal("replayed counter did not raise a clone warning")
+	}
+}
+
+func mustOptions(t *testing.T, v interface{}) CeremonyOptions {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := ParseCeremonyOptions(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
@@ -0,0 +1,7 @@
+CREATE TABLE siwe_nonces (
+    nonce TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX siwe_nonces_expires_at_idx ON siwe_nonces(expires_at);
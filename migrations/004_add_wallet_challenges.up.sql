@@ -0,0 +1,10 @@
+CREATE TABLE wallet_challenges (
+    nonce TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    wallet_address TEXT NOT NULL,
+    message TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX wallet_challenges_user_id_idx ON wallet_challenges(user_id);
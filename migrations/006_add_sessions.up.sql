@@ -0,0 +1,21 @@
+CREATE TABLE sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions(user_id);
+
+-- Refresh tokens issued before sessions existed cannot be attributed to one,
+-- so those clients have to sign in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+ADD COLUMN session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE;
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens(session_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMPTZ,
+ADD COLUMN email_verification_sent_at TIMESTAMPTZ;
@@ -0,0 +1,33 @@
+CREATE TABLE webauthn_user_handles (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    handle BYTEA NOT NULL UNIQUE
+);
+
+CREATE TABLE webauthn_credentials (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    attestation_type TEXT NOT NULL DEFAULT '',
+    transports TEXT NOT NULL DEFAULT '',
+    aaguid BYTEA,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+    name TEXT NOT NULL DEFAULT '',
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials(user_id);
+
+CREATE TABLE webauthn_challenges (
+    id TEXT PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    ceremony TEXT NOT NULL,
+    session_data JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webauthn_challenges_expires_at_idx ON webauthn_challenges(expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS username_redirects;
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN pending_email TEXT;
+
+CREATE TABLE username_redirects (
+    old_username TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX username_redirects_user_id_idx ON username_redirects(user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS username_redirects_old_username_lower_idx;
+DROP INDEX IF EXISTS username_redirects_skeleton_idx;
+ALTER TABLE username_redirects DROP COLUMN IF EXISTS skeleton;
+DROP INDEX IF EXISTS users_username_skeleton_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS username_skeleton;
+DROP INDEX IF EXISTS users_username_lower_idx;
@@ -0,0 +1,24 @@
+-- Fails if two accounts already differ only by case; run `make check-emails`
+-- to list them and rename one first. The API runs the same check before
+-- migrating.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM users
+        WHERE username IS NOT NULL
+        GROUP BY LOWER(username)
+        HAVING COUNT(*) > 1
+    ) THEN
+        RAISE EXCEPTION 'some accounts have usernames that differ only by case; run `make check-emails` to list them and rename them before migrating';
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX users_username_lower_idx ON users (LOWER(username));
+
+-- Filled in by the API for new names and, at startup, for existing ones.
+ALTER TABLE users ADD COLUMN username_skeleton TEXT;
+CREATE UNIQUE INDEX users_username_skeleton_idx ON users (username_skeleton);
+
+ALTER TABLE username_redirects ADD COLUMN skeleton TEXT;
+CREATE INDEX username_redirects_skeleton_idx ON username_redirects (skeleton);
+CREATE INDEX username_redirects_old_username_lower_idx ON username_redirects (LOWER(old_username));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_pending_email_lower_idx;
+DROP INDEX IF EXISTS users_email_canonical_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS email_canonical;
+DROP INDEX IF EXISTS users_email_lower_idx;
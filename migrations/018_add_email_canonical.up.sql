@@ -0,0 +1,22 @@
+-- Fails if two accounts already differ only by case; run `make check-emails`
+-- first to list them. The API runs the same check before migrating.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM users
+        WHERE email IS NOT NULL
+        GROUP BY LOWER(email)
+        HAVING COUNT(*) > 1
+    ) THEN
+        RAISE EXCEPTION 'some accounts have emails that differ only by case; run `make check-emails` to list them and resolve them before migrating';
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX users_email_lower_idx ON users (LOWER(email));
+
+-- LOWER(email) unless EMAIL_CANONICALIZATION was on when the address was added.
+ALTER TABLE users ADD COLUMN email_canonical TEXT;
+UPDATE users SET email_canonical = LOWER(email) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX users_email_canonical_idx ON users (email_canonical);
+
+CREATE INDEX users_pending_email_lower_idx ON users (LOWER(pending_email));
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN suspension_reason TEXT;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS trees_user_id_idx;
+DROP INDEX IF EXISTS trees_user_default_idx;
+DROP INDEX IF EXISTS trees_slug_idx;
+ALTER TABLE trees
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS is_default,
+    DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,36 @@
+ALTER TABLE trees
+    ADD COLUMN slug TEXT,
+    ADD COLUMN is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Each user's first tree becomes their default. Slugs come from the username,
+-- with the tree ID appended where that is taken or there is no username.
+-- Names already ending in -<digits> always get the ID too, so they cannot
+-- clash with an appended one.
+WITH named AS (
+    SELECT
+        t.id,
+        ROW_NUMBER() OVER (PARTITION BY t.user_id ORDER BY t.id) AS n,
+        COALESCE(
+            NULLIF(TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(u.username), '[^a-z0-9]+', '-', 'g')), ''),
+            'tree'
+        ) AS base
+    FROM trees t
+    JOIN users u ON u.id = t.user_id
+), numbered AS (
+    SELECT id, n, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY n, id) AS taken
+    FROM named
+)
+UPDATE trees t
+SET slug = CASE
+        WHEN nb.taken = 1 AND nb.base <> 'tree' AND LENGTH(nb.base) >= 3 AND nb.base !~ '-[0-9]+$' THEN nb.base
+        ELSE nb.base || '-' || t.id
+    END,
+    is_default = (nb.n = 1)
+FROM numbered nb
+WHERE nb.id = t.id;
+
+ALTER TABLE trees ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX trees_slug_idx ON trees (slug);
+CREATE UNIQUE INDEX trees_user_default_idx ON trees (user_id) WHERE is_default;
+CREATE INDEX trees_user_id_idx ON trees (user_id);
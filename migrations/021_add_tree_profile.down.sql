@@ -0,0 +1,5 @@
+ALTER TABLE trees
+    DROP COLUMN IF EXISTS socials,
+    DROP COLUMN IF EXISTS avatar_url,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,5 @@
+ALTER TABLE trees
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN socials JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+ALTER TABLE trees
+    DROP COLUMN IF EXISTS theme_overrides,
+    DROP COLUMN IF EXISTS theme_preset;
@@ -0,0 +1,3 @@
+ALTER TABLE trees
+    ADD COLUMN theme_preset TEXT NOT NULL DEFAULT 'classic',
+    ADD COLUMN theme_overrides JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE trees
+    DROP COLUMN IF EXISTS auto_publish,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS published;
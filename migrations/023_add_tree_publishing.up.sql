@@ -0,0 +1,33 @@
+ALTER TABLE trees
+    ADD COLUMN published JSONB,
+    ADD COLUMN published_at TIMESTAMPTZ,
+    ADD COLUMN auto_publish BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Existing trees keep going live on every edit, as their owners expect; trees
+-- created from now on start with drafts.
+ALTER TABLE trees ALTER COLUMN auto_publish SET DEFAULT FALSE;
+
+-- Existing trees were live as they are, so their current content becomes
+-- their published version.
+UPDATE trees t
+SET published = jsonb_build_object(
+        'title', t.title,
+        'display_name', t.display_name,
+        'bio', t.bio,
+        'avatar_url', t.avatar_url,
+        'socials', t.socials,
+        'theme_preset', t.theme_preset,
+        'theme_overrides', t.theme_overrides,
+        'links', COALESCE((
+            SELECT jsonb_agg(jsonb_build_object(
+                       'id', l.id,
+                       'title', l.title,
+                       'url', l.url,
+                       'position', l.position,
+                       'is_active', l.is_active
+                   ) ORDER BY l.position, l.id)
+            FROM links l
+            WHERE l.tree_id = t.id
+        ), '[]'::jsonb)
+    ),
+    published_at = NOW();
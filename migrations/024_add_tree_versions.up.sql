@@ -0,0 +1,16 @@
+CREATE TABLE tree_versions (
+    id BIGSERIAL PRIMARY KEY,
+    tree_id BIGINT NOT NULL REFERENCES trees(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    snapshot JSONB NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'publish', 'auto_publish', 'restore')),
+    author_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tree_id, version)
+);
+
+-- What is published now becomes version 1. Who published it is not known.
+INSERT INTO tree_versions (tree_id, version, snapshot, action, created_at)
+SELECT id, 1, published, 'publish', COALESCE(published_at, NOW())
+FROM trees
+WHERE published IS NOT NULL;